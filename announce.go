@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ratorx/chumenu-go/timeofday"
+)
+
+const (
+	announcementBucket = "announcements"
+	announceTimeFormat = "Mon 2 Jan 15:04"
+
+	// Announcement messages
+	announceUsage     = "Usage: *announce [at <time>] <message>*, *announce preview <message>*, *announce list* or *announce cancel <number>*"
+	announceScheduled = "Announcement %d scheduled for %s."
+	announceNone      = "No announcements scheduled."
+	announceCancelled = "Announcement %d cancelled."
+	announceNotFound  = "No announcement %v scheduled."
+	announceMissed    = "Announcement %d for %s was not sent on time, so it was dropped: %s"
+)
+
+// announcement is a message to every subscriber which is sent at a later time
+type announcement struct {
+	ID   uint64 `json:"-"`
+	At   time.Time
+	Text string
+}
+
+func (a announcement) String() string {
+	return fmt.Sprintf("%d. %s: %s", a.ID, a.At.In(cfg.location).Format(announceTimeFormat), a.Text)
+}
+
+func announcementKey(id uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, id)
+	return k
+}
+
+// announceTime returns the next time after t at the wall clock time in text
+func announceTime(text string, t time.Time) (time.Time, error) {
+	tod, err := timeofday.Parse(text)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	at := tod.On(t, cfg.location)
+	if !at.After(t) {
+		at = tod.On(t.AddDate(0, 0, 1), cfg.location)
+	}
+
+	return at, nil
+}
+
+// scheduleAnnouncement stores an announcement of text to be sent at the time at, and returns its number
+func scheduleAnnouncement(at time.Time, text string) (uint64, error) {
+	var id uint64
+
+	err := cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(announcementBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", announcementBucket)
+		}
+
+		var err error
+		if id, err = b.NextSequence(); err != nil {
+			return err
+		}
+
+		v, err := json.Marshal(announcement{At: at, Text: text})
+		if err != nil {
+			return err
+		}
+
+		return b.Put(announcementKey(id), v)
+	})
+
+	return id, err
+}
+
+// pendingAnnouncements returns the announcements which have not been sent, in the order they are due
+func pendingAnnouncements() ([]announcement, error) {
+	var pending []announcement
+
+	err := cfg.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(announcementBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", announcementBucket)
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			a := announcement{ID: binary.BigEndian.Uint64(k)}
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+			pending = append(pending, a)
+			return nil
+		})
+	})
+
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].At.Before(pending[j].At) })
+	return pending, err
+}
+
+// cancelAnnouncement removes the pending announcement with the given number, and reports whether there was one
+func cancelAnnouncement(id uint64) (bool, error) {
+	found := false
+
+	err := cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(announcementBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", announcementBucket)
+		}
+
+		k := announcementKey(id)
+		if b.Get(k) == nil {
+			return nil
+		}
+
+		found = true
+		return b.Delete(k)
+	})
+
+	return found, err
+}
+
+// takeAnnouncements removes and returns the pending announcements which are due by the time at, in the order they
+// are due. They are removed in the same transaction as they are read, so one cancelled meanwhile is not sent.
+func takeAnnouncements(at time.Time) ([]announcement, error) {
+	var due []announcement
+
+	err := cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(announcementBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", announcementBucket)
+		}
+
+		err := b.ForEach(func(k, v []byte) error {
+			a := announcement{ID: binary.BigEndian.Uint64(k)}
+			if err := json.Unmarshal(v, &a); err != nil { // nolint: vetshadow
+				return err
+			}
+			if !a.At.After(at) {
+				due = append(due, a)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, a := range due {
+			if err = b.Delete(announcementKey(a.ID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].At.Before(due[j].At) })
+	return due, nil
+}
+
+// sendAnnouncements sends the announcements due by the time at.
+// Announcements missed by more than maxCatchUp, e.g. while the server was down, are dropped and the admin is told.
+func sendAnnouncements(at time.Time) {
+	due, err := takeAnnouncements(at)
+	if err != nil {
+		cfg.debug.Print(err)
+		return
+	}
+
+	for _, a := range due {
+		if at.Sub(a.At) > maxCatchUp {
+			adminMessage(fmt.Sprintf(announceMissed, a.ID, a.At.In(cfg.location).Format(announceTimeFormat), a.Text))
+			continue
+		}
+
+		announceMessage(a.Text)
+	}
+}
+
+// announceHandler sends, schedules, previews, lists or cancels announcements from text of the form
+// "[at <time>] <message>", "preview <message>", "list" or "cancel <number>". The message keeps its original case.
+func announceHandler(sender, text string) {
+	if sender != cfg.admin {
+		responseMessage(sender, adminOnly, standardQR)
+		return
+	}
+
+	words := strings.SplitN(text, " ", 2)
+	command, rest := strings.ToLower(words[0]), ""
+	if len(words) == 2 {
+		rest = strings.TrimSpace(words[1])
+	}
+
+	switch {
+	case command == "list":
+		if rest != "" {
+			responseMessage(sender, announceUsage, standardQR)
+			return
+		}
+
+		pending, err := pendingAnnouncements()
+		if err != nil {
+			cfg.debug.Print(err)
+			responseMessage(sender, unexpected, standardQR)
+			return
+		}
+
+		if len(pending) == 0 {
+			responseMessage(sender, announceNone, standardQR)
+			return
+		}
+
+		lines := make([]string, len(pending))
+		for i, a := range pending {
+			lines[i] = a.String()
+		}
+		responseMessage(sender, strings.Join(lines, "\n"), standardQR)
+	case command == "cancel":
+		id, err := strconv.ParseUint(rest, 10, 64)
+		if err != nil {
+			responseMessage(sender, announceUsage, standardQR)
+			return
+		}
+
+		found, err := cancelAnnouncement(id)
+		switch {
+		case err != nil:
+			cfg.debug.Print(err)
+			responseMessage(sender, unexpected, standardQR)
+		case !found:
+			responseMessage(sender, fmt.Sprintf(announceNotFound, id), standardQR)
+		default:
+			responseMessage(sender, fmt.Sprintf(announceCancelled, id), standardQR)
+		}
+	case command == "preview":
+		if rest == "" {
+			responseMessage(sender, announceUsage, standardQR)
+			return
+		}
+
+		// Sent as subscribers receive it
+		subscriptionMessage(sender, rest, subscriptionQR)
+	case command == "at":
+		words = strings.SplitN(rest, " ", 2)
+		if len(words) != 2 || strings.TrimSpace(words[1]) == "" {
+			responseMessage(sender, announceUsage, standardQR)
+			return
+		}
+
+		at, err := announceTime(words[0], now())
+		if err != nil {
+			responseMessage(sender, announceUsage, standardQR)
+			return
+		}
+
+		id, err := scheduleAnnouncement(at, strings.TrimSpace(words[1]))
+		if err != nil {
+			cfg.debug.Print(err)
+			responseMessage(sender, unexpected, standardQR)
+			return
+		}
+		responseMessage(sender, fmt.Sprintf(announceScheduled, id, at.Format(announceTimeFormat)), standardQR)
+	case command == "":
+		responseMessage(sender, announceUsage, standardQR)
+	default:
+		announceMessage(text)
+	}
+}
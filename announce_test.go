@@ -0,0 +1,165 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ratorx/chumenu-go/facebook"
+	"github.com/ratorx/chumenu-go/timeofday"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnounceTime(t *testing.T) {
+	testLocation(t)
+	// Midday on Monday 3rd June 2019
+	current := time.Date(2019, time.June, 3, 12, 0, 0, 0, cfg.location)
+
+	for _, c := range []struct {
+		text     string
+		expected time.Time
+	}{
+		{"17:00", time.Date(2019, time.June, 3, 17, 0, 0, 0, cfg.location)},
+		{"12:01", time.Date(2019, time.June, 3, 12, 1, 0, 0, cfg.location)},
+		// Times which have passed are tomorrow
+		{"12:00", time.Date(2019, time.June, 4, 12, 0, 0, 0, cfg.location)},
+		{"9:30", time.Date(2019, time.June, 4, 9, 30, 0, 0, cfg.location)},
+	} {
+		at, err := announceTime(c.text, current)
+		require.NoError(t, err, c.text)
+		assert.True(t, c.expected.Equal(at), "%v: got %v", c.text, at)
+	}
+
+	_, err := announceTime("teatime", current)
+	assert.Error(t, err)
+}
+
+func TestAnnouncements(t *testing.T) {
+	_, cleanup := testConfig(t)
+	defer cleanup()
+
+	evening := time.Date(2019, time.June, 3, 17, 0, 0, 0, cfg.location)
+	first, err := scheduleAnnouncement(evening.Add(time.Hour), "Formal Hall is Black Tie")
+	require.NoError(t, err)
+	second, err := scheduleAnnouncement(evening, "BBQ on the Lawn")
+	require.NoError(t, err)
+	third, err := scheduleAnnouncement(evening.AddDate(0, 0, 1), "Bar closed")
+	require.NoError(t, err)
+
+	pending, err := pendingAnnouncements()
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+	assert.Equal(t, []uint64{second, first, third}, []uint64{pending[0].ID, pending[1].ID, pending[2].ID}, "Not in the order they are due")
+	assert.Equal(t, "BBQ on the Lawn", pending[0].Text, "Case not kept")
+
+	found, err := cancelAnnouncement(third)
+	require.NoError(t, err)
+	assert.True(t, found)
+	found, err = cancelAnnouncement(third)
+	require.NoError(t, err)
+	assert.False(t, found, "Cancelled twice")
+
+	due, err := takeAnnouncements(evening.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, second, due[0].ID)
+
+	// Taken announcements are not sent again
+	pending, err = pendingAnnouncements()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, first, pending[0].ID)
+}
+
+func TestAnnounceHandler(t *testing.T) {
+	_, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+	setClock(3, timeofday.New(12, 0))
+
+	subscribeHandler(testUser, "")
+	out.to(testUser, 1)
+
+	// Only the admin can announce
+	announceHandler(testUser, "at 17:00 Free Pizza")
+	assert.Equal(t, adminOnly, out.last(testUser, 2))
+	announceHandler(testUser, "Free Pizza")
+	assert.Equal(t, adminOnly, out.last(testUser, 3))
+	pending, err := pendingAnnouncements()
+	require.NoError(t, err)
+	assert.Empty(t, pending, "Announcement scheduled by a user")
+
+	sent := 0
+	for _, c := range []struct {
+		text  string
+		reply string
+	}{
+		{"at 17:00 Formal Hall is Black Tie", "Announcement 1 scheduled for Mon 3 Jun 17:00."},
+		{"at 9:30 Bar closed", "Announcement 2 scheduled for Tue 4 Jun 09:30."},
+		{"at teatime Cake", announceUsage},
+		{"at 17:00", announceUsage},
+		{"preview Formal Hall is Black Tie", "Formal Hall is Black Tie"},
+		{"preview", announceUsage},
+		{"preview  ", announceUsage},
+		{"list", "1. Mon 3 Jun 17:00: Formal Hall is Black Tie\n2. Tue 4 Jun 09:30: Bar closed"},
+		{"list foo", announceUsage},
+		{"", announceUsage},
+		{"cancel 3", "No announcement 3 scheduled."},
+		{"cancel two", announceUsage},
+		{"cancel 2", "Announcement 2 cancelled."},
+		{"list", "1. Mon 3 Jun 17:00: Formal Hall is Black Tie"},
+	} {
+		announceHandler(testAdmin, c.text)
+		sent++
+		assert.Equal(t, c.reply, out.last(testAdmin, sent), c.text)
+	}
+	assert.Len(t, out.to(testUser, 3), 3, "Preview sent to subscribers")
+
+	// Announcements without a time are sent straight away
+	announceHandler(testAdmin, "Bar open late")
+	assert.Equal(t, "Bar open late", out.last(testUser, 4))
+}
+
+func TestHandleEvent_AnnouncePrefix(t *testing.T) {
+	_, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+
+	subscribeHandler(testUser, "")
+	out.to(testUser, 1)
+
+	// The message keeps its case, and a prefix with a space is removed
+	e := eventHandler{commandPrefix: "bot "}
+	e.HandleEvent([]facebook.MessagingEvent{{Sender: facebook.Recipient{ID: testAdmin}, Message: &facebook.Message{Text: "Bot Announce Bar Open Late"}}})
+	assert.Equal(t, "Bar Open Late", out.last(testUser, 2))
+}
+
+func TestSendAnnouncements(t *testing.T) {
+	_, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+
+	subscribeHandler(testUser, "")
+	out.to(testUser, 1)
+
+	evening := time.Date(2019, time.June, 3, 17, 0, 0, 0, cfg.location)
+	_, err := scheduleAnnouncement(evening.Add(-8*time.Hour), "Brunch moved")
+	require.NoError(t, err)
+	_, err = scheduleAnnouncement(evening, "BBQ on the Lawn")
+	require.NoError(t, err)
+	_, err = scheduleAnnouncement(evening.Add(time.Hour), "Formal Hall is Black Tie")
+	require.NoError(t, err)
+
+	// Caught up a few minutes late, the announcement missed by hours is dropped
+	sendAnnouncements(evening.Add(5 * time.Minute))
+	assert.Equal(t, "BBQ on the Lawn", out.last(testUser, 2))
+	assert.Equal(t, "Announcement 1 for Mon 3 Jun 09:00 was not sent on time, so it was dropped: Brunch moved", out.last(testAdmin, 1))
+
+	pending, err := pendingAnnouncements()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "Formal Hall is Black Tie", pending[0].Text)
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ratorx/chumenu-go/menus"
+)
+
+const (
+	calendarBucket = "calendar"
+	closuresKey    = "closures"
+	maxClosures    = 5 // upcoming closures listed by the times command
+
+	// Calendar messages
+	hallClosed       = "%s is closed until %s."
+	closuresTitle    = "Upcoming closures:"
+	closeUsage       = "Usage: *close <date> [to <date>][: reason]*"
+	closeSuccess     = "%s closed from %s to %s."
+	reopenSuccess    = "%s reopened on %s."
+	reopenUsage      = "Usage: *reopen <date>*"
+	reopenFail       = "%s has no closure on %s which can be removed."
+	adminOnly        = "Only the admin can do that."
+	closureDayFormat = "Mon 2 Jan"
+)
+
+// term is a period in which the halls are open as usual.
+// Dates are in the form YYYY-MM-DD, matching menus.DateKey, so they can be compared as strings.
+type term struct {
+	Name  string `json:"name"`
+	Start string `json:"start"` // first day of term
+	End   string `json:"end"`   // last day of term
+}
+
+// closure is a period in which a venue is closed
+type closure struct {
+	Venue  string `json:"venue,omitempty"` // venue name, every venue if empty
+	Start  string `json:"start"`           // first closed day
+	End    string `json:"end"`             // last closed day
+	Reason string `json:"reason,omitempty"`
+}
+
+// covers reports whether the closure closes v on the date of t
+func (c closure) covers(v *venue, t time.Time) bool {
+	key := menus.DateKey(t)
+	return (c.Venue == "" || c.Venue == v.name) && c.Start <= key && key <= c.End
+}
+
+func (c closure) String() string {
+	start, _ := time.Parse("2006-01-02", c.Start)
+	end, _ := time.Parse("2006-01-02", c.End)
+
+	text := start.Format(closureDayFormat)
+	if c.End != c.Start {
+		text += " - " + end.Format(closureDayFormat)
+	}
+	if c.Reason != "" {
+		text += ": " + c.Reason
+	}
+
+	return text
+}
+
+// calendar contains the term dates and the closures known in advance
+type calendar struct {
+	Terms    []term    `json:"terms"`
+	Closures []closure `json:"closures"`
+}
+
+// Checks that every date in the calendar is in the form YYYY-MM-DD, and that periods don't end before they start
+func validPeriod(start, end string) error {
+	for _, d := range []string{start, end} {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return fmt.Errorf("calendar: invalid date %q (expected YYYY-MM-DD)", d)
+		}
+	}
+
+	if end < start {
+		return fmt.Errorf("calendar: %v ends before it starts on %v", end, start)
+	}
+
+	return nil
+}
+
+// Reads a calendar from the JSON file at path
+func loadCalendar(path string) (calendar, error) {
+	var c calendar
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+
+	if err = json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("calendar: %v", err)
+	}
+
+	for _, t := range c.Terms {
+		if err = validPeriod(t.Start, t.End); err != nil {
+			return c, err
+		}
+	}
+
+	for i := range c.Closures {
+		if err = validPeriod(c.Closures[i].Start, c.Closures[i].End); err != nil {
+			return c, err
+		}
+		c.Closures[i].Venue = strings.ToLower(c.Closures[i].Venue)
+	}
+
+	return c, nil
+}
+
+// inTerm reports whether the date of t is in term. Every date is in term if no terms are known.
+func (c calendar) inTerm(t time.Time) bool {
+	if len(c.Terms) == 0 {
+		return true
+	}
+
+	key := menus.DateKey(t)
+	for _, t := range c.Terms {
+		if t.Start <= key && key <= t.End {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the closures added by the admin
+func loadClosures(b *bolt.Bucket) ([]closure, error) {
+	var closures []closure
+
+	v := b.Get([]byte(closuresKey))
+	if v == nil {
+		return closures, nil
+	}
+
+	err := json.Unmarshal(v, &closures)
+	return closures, err
+}
+
+func putClosures(b *bolt.Bucket, closures []closure) error {
+	v, err := json.Marshal(closures)
+	if err != nil {
+		return err
+	}
+
+	return b.Put([]byte(closuresKey), v)
+}
+
+// getClosures returns the closures from the calendar file and those added by the admin, ordered by start date
+func getClosures() []closure {
+	closures := append([]closure{}, cfg.calendar.Closures...)
+
+	err := cfg.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(calendarBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", calendarBucket)
+		}
+
+		added, err := loadClosures(b)
+		closures = append(closures, added...)
+		return err
+	})
+
+	if err != nil {
+		cfg.debug.Print(err)
+	}
+
+	sort.SliceStable(closures, func(i, j int) bool { return closures[i].Start < closures[j].Start })
+	return closures
+}
+
+// closedError is returned by getMenu if the venue is closed on the day of the next meal
+type closedError struct {
+	venue *venue
+	until time.Time // date the venue reopens
+}
+
+func (e *closedError) Error() string {
+	return fmt.Sprintf(hallClosed, e.venue.display, e.until.Format(closureDayFormat))
+}
+
+// closedUntil returns the date v reopens if it is closed on the date of t
+func closedUntil(v *venue, t time.Time) (time.Time, bool) {
+	closures := getClosures()
+
+	closed := false
+	for {
+		found := false
+		for _, c := range closures {
+			if c.covers(v, t) {
+				end, _ := time.Parse("2006-01-02", c.End)
+				y, m, d := end.AddDate(0, 0, 1).Date()
+				t = time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+				closed, found = true, true
+			}
+		}
+
+		// Keep going in case another closure starts the day this one ends
+		if !found {
+			return t, closed
+		}
+	}
+}
+
+// upcomingClosures returns the closures of v which have not ended by the date of t
+func upcomingClosures(v *venue, t time.Time) []closure {
+	var ret []closure
+	for _, c := range getClosures() {
+		if (c.Venue == "" || c.Venue == v.name) && c.End >= menus.DateKey(t) {
+			ret = append(ret, c)
+		}
+	}
+
+	return ret
+}
+
+// parseClosure reads the first and last closed days and the reason from text of the form "<date> [to <date>][: reason]".
+// Dates are completed relative to t, and the end relative to the start.
+func parseClosure(text string, t time.Time) (start, end time.Time, reason string, err error) {
+	if i := strings.Index(text, ":"); i >= 0 {
+		text, reason = text[:i], strings.TrimSpace(text[i+1:])
+	}
+
+	dates := strings.SplitN(text, " to ", 2)
+	if start, err = menus.ParseDate(dates[0], t); err != nil {
+		return
+	}
+
+	end = start
+	if len(dates) == 2 {
+		if end, err = menus.ParseDate(dates[1], start); err != nil {
+			return
+		}
+		if end.Before(start) {
+			err = fmt.Errorf("calendar: closure ends before it starts")
+		}
+	}
+
+	return
+}
+
+// closeHandler adds a closure to the admin's venue from text of the form "<date> [to <date>][: reason]"
+func closeHandler(sender, text string) {
+	if sender != cfg.admin {
+		responseMessage(sender, adminOnly, standardQR)
+		return
+	}
+
+	start, end, reason, err := parseClosure(text, now())
+	if err != nil {
+		responseMessage(sender, closeUsage, standardQR)
+		return
+	}
+
+	v := getVenue(getPreferences(sender).Venue)
+	c := closure{Venue: v.name, Start: menus.DateKey(start), End: menus.DateKey(end), Reason: reason}
+
+	err = cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(calendarBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", calendarBucket)
+		}
+
+		closures, err := loadClosures(b) // nolint: vetshadow
+		if err != nil {
+			return err
+		}
+
+		return putClosures(b, append(closures, c))
+	})
+
+	if err != nil {
+		cfg.debug.Print(err)
+		responseMessage(sender, unexpected, standardQR)
+		return
+	}
+
+	responseMessage(sender, fmt.Sprintf(closeSuccess, v.display, start.Format(closureDayFormat), end.Format(closureDayFormat)), standardQR)
+}
+
+// reopenHandler removes the closures added by the admin which cover the date in text at the admin's venue
+func reopenHandler(sender, text string) {
+	if sender != cfg.admin {
+		responseMessage(sender, adminOnly, standardQR)
+		return
+	}
+
+	date, err := menus.ParseDate(text, now())
+	if err != nil {
+		responseMessage(sender, reopenUsage, standardQR)
+		return
+	}
+
+	v := getVenue(getPreferences(sender).Venue)
+	day := date.Format(closureDayFormat)
+
+	reopened := false
+	err = cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(calendarBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", calendarBucket)
+		}
+
+		closures, err := loadClosures(b) // nolint: vetshadow
+		if err != nil {
+			return err
+		}
+
+		kept := closures[:0]
+		for _, c := range closures {
+			if !c.covers(v, date) {
+				kept = append(kept, c)
+			}
+		}
+
+		if len(kept) == len(closures) {
+			return nil
+		}
+
+		reopened = true
+		return putClosures(b, kept)
+	})
+
+	switch {
+	case err != nil:
+		cfg.debug.Print(err)
+		responseMessage(sender, unexpected, standardQR)
+	case reopened:
+		responseMessage(sender, fmt.Sprintf(reopenSuccess, v.display, day), standardQR)
+	default:
+		responseMessage(sender, fmt.Sprintf(reopenFail, v.display, day), standardQR)
+	}
+}
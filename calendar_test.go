@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ratorx/chumenu-go/menus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendar_InTerm(t *testing.T) {
+	testLocation(t)
+	terms := calendar{Terms: []term{
+		{Name: "Michaelmas", Start: "2018-10-02", End: "2018-11-30"},
+		{Name: "Lent", Start: "2019-01-15", End: "2019-03-15"},
+	}}
+
+	for _, c := range []struct {
+		calendar calendar
+		date     time.Time
+		expected bool
+	}{
+		{terms, time.Date(2018, time.October, 1, 23, 59, 0, 0, cfg.location), false},
+		{terms, time.Date(2018, time.October, 2, 0, 0, 0, 0, cfg.location), true},
+		{terms, time.Date(2018, time.November, 30, 23, 59, 0, 0, cfg.location), true},
+		{terms, time.Date(2018, time.December, 25, 12, 0, 0, 0, cfg.location), false},
+		{terms, time.Date(2019, time.February, 1, 12, 0, 0, 0, cfg.location), true},
+		// Every day is in term without term dates
+		{calendar{}, time.Date(2018, time.December, 25, 12, 0, 0, 0, cfg.location), true},
+	} {
+		assert.Equal(t, c.expected, c.calendar.inTerm(c.date), "%v", c.date)
+	}
+}
+
+func TestClosedUntil(t *testing.T) {
+	v, cleanup := testConfig(t)
+	defer cleanup()
+
+	cfg.calendar.Closures = []closure{
+		{Venue: "test", Start: "2019-06-04", End: "2019-06-04"},
+		// Adjacent closures are chained
+		{Start: "2019-06-06", End: "2019-06-07"},
+		{Venue: "test", Start: "2019-06-08", End: "2019-06-08"},
+		// Other venues' closures are ignored
+		{Venue: "other", Start: "2019-06-10", End: "2019-06-12"},
+	}
+
+	day := func(d int) time.Time { return time.Date(2019, time.June, d, 0, 0, 0, 0, cfg.location) }
+	for _, c := range []struct {
+		date   time.Time
+		closed bool
+		until  time.Time
+	}{
+		{day(3), false, day(3)},
+		{day(4).Add(12 * time.Hour), true, day(5)},
+		{day(5), false, day(5)},
+		{day(6), true, day(9)},
+		{day(7).Add(18 * time.Hour), true, day(9)},
+		{day(9), false, day(9)},
+		{day(10), false, day(10)},
+	} {
+		until, closed := closedUntil(v, c.date)
+		assert.Equal(t, c.closed, closed, "%v", c.date)
+		assert.Equal(t, menus.DateKey(c.until), menus.DateKey(until), "%v", c.date)
+	}
+}
+
+func TestParseClosure(t *testing.T) {
+	testLocation(t)
+	// Monday 3rd June 2019
+	ref := time.Date(2019, time.June, 3, 12, 0, 0, 0, cfg.location)
+
+	for _, c := range []struct {
+		text       string
+		start, end string
+		reason     string
+	}{
+		{"2019-06-04", "2019-06-04", "2019-06-04", ""},
+		{"friday", "2019-06-07", "2019-06-07", ""},
+		{"4/6 to 6/6", "2019-06-04", "2019-06-06", ""},
+		{"4/6 to 6/6: Staff training", "2019-06-04", "2019-06-06", "Staff training"},
+		// The end is completed relative to the start
+		{"2019-12-20 to 3 january: Christmas", "2019-12-20", "2020-01-03", "Christmas"},
+	} {
+		start, end, reason, err := parseClosure(c.text, ref)
+		require.NoError(t, err, c.text)
+		assert.Equal(t, c.start, menus.DateKey(start), c.text)
+		assert.Equal(t, c.end, menus.DateKey(end), c.text)
+		assert.Equal(t, c.reason, reason, c.text)
+	}
+
+	for _, text := range []string{"", "soon", "6/6 to 4/6", "4/6 to later"} {
+		_, _, _, err := parseClosure(text, ref)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestCloseReopenHandlers(t *testing.T) {
+	v, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+	setClock(3, lunchTime.Start)
+
+	closeHandler(testUser, "4/6: Staff training")
+	assert.Equal(t, adminOnly, out.last(testUser, 1))
+	assert.Empty(t, getClosures(), "Closure added by a user")
+
+	closeHandler(testAdmin, "whenever")
+	assert.Equal(t, closeUsage, out.last(testAdmin, 1))
+
+	closeHandler(testAdmin, "4/6 to 5/6: Staff training")
+	assert.Equal(t, "Test closed from Tue 4 Jun to Wed 5 Jun.", out.last(testAdmin, 2))
+	assert.Equal(t, []closure{{Venue: "test", Start: "2019-06-04", End: "2019-06-05", Reason: "Staff training"}}, getClosures())
+
+	reopenHandler(testAdmin, "6/6")
+	assert.Equal(t, "Test has no closure on Thu 6 Jun which can be removed.", out.last(testAdmin, 3))
+
+	reopenHandler(testAdmin, "5/6")
+	assert.Equal(t, "Test reopened on Wed 5 Jun.", out.last(testAdmin, 4))
+	assert.Empty(t, getClosures())
+	_, closed := closedUntil(v, time.Date(2019, time.June, 4, 12, 0, 0, 0, cfg.location))
+	assert.False(t, closed)
+}
@@ -0,0 +1,97 @@
+// Records parser fixtures from saved menu pages
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ratorx/chumenu-go/menus"
+)
+
+func main() {
+	ref := flag.String("ref", "", "date the pages were saved (YYYY-MM-DD), defaults to today")
+	rulesFile := flag.String("rules", "", "post-processing rules file, defaults to the built-in rules")
+	url := flag.String("url", "", "download the live menu page from url and save it as page.html first, e.g. "+menus.ChurchillURL)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: record [-ref date] [-rules file] <page.html>...")
+		fmt.Fprintln(os.Stderr, "       record -url url [-rules file] <page.html>")
+		fmt.Fprintln(os.Stderr, "Writes the parsed result of each page to page.json")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 || (*url != "" && (flag.NArg() != 1 || *ref != "")) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *url != "" {
+		if err := save(*url, flag.Arg(0)); err != nil {
+			panic(err)
+		}
+	}
+
+	date := time.Now().UTC()
+	if *ref != "" {
+		var err error
+		date, err = time.Parse("2006-01-02", *ref)
+		if err != nil {
+			panic(err)
+		}
+	}
+	// Midday avoids ambiguity in which day the page was saved
+	date = time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, time.UTC)
+
+	rules := menus.DefaultRules
+	if *rulesFile != "" {
+		var err error
+		rules, err = menus.LoadRules(*rulesFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	for _, page := range flag.Args() {
+		fixture, err := menus.RecordFixture(page, date, rules)
+		if err != nil {
+			panic(err)
+		}
+
+		b, err := json.MarshalIndent(fixture, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+
+		out := strings.TrimSuffix(page, ".html") + ".json"
+		if err = ioutil.WriteFile(out, append(b, '\n'), 0644); err != nil {
+			panic(err)
+		}
+		fmt.Printf("%v: %v days, %v warnings -> %v\n", page, len(fixture.Week), len(fixture.Warnings), out)
+	}
+}
+
+// Saves the page at url to path, unchanged so that it is parsed exactly as the live page is
+func save(url, path string) error {
+	res, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("record: %v returned %v", url, res.Status)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0644)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ratorx/chumenu-go/menus"
+	"github.com/ratorx/chumenu-go/timeofday"
+)
+
+// Digest modes, which replace the messages sent before each meal
+const (
+	noDigest     = ""
+	dailyDigest  = "daily"
+	weeklyDigest = "weekly"
+
+	weeklyDigestDay = time.Sunday
+	digestDayFormat = "Mon 2 Jan"
+
+	// Digest messages
+	digestUsage     = "Use *digest daily <time>* for one message a day, *digest weekly* for a Sunday evening overview of the coming week, or *digest off* for a message before each meal."
+	digestSubscribe = "Subscribe first to receive digests."
+	digestDaily     = "Daily digest will be sent at %s."
+	digestWeekly    = "Weekly digest will be sent on %ss at %s."
+	digestOff       = "Digest turned off. Menus will be sent before each meal."
+	digestClosed    = "Closed"
+	digestNextWeek  = "Next week's menu has not been published yet. Use *menu* during the week to see each meal."
+)
+
+var (
+	defaultDigestTime = timeofday.New(8, 0)
+	weeklyDigestTime  = timeofday.New(18, 0)
+)
+
+// summary renders the meals on a menu which the subscriber receives, one line per meal, filtered for their diet
+func summary(v *venue, m menus.Menu, s subscriber, d diet) []string {
+	var lines []string
+	for _, nm := range m.Meals {
+		if _, served := v.mealTime(nm.Name, m.Date); !served || !s.wants(nm.Name) {
+			continue
+		}
+
+		filtered, _ := d.filter(nm.Items)
+		lines = append(lines, fmt.Sprintf("*%s*: %s", nm.Name, filtered.Summary()))
+	}
+
+	return lines
+}
+
+// renderDay formats the digest of the menu for a single day
+func renderDay(v *venue, week menus.Week, date time.Time, s subscriber, d diet) string {
+	heading := date.Format(digestDayFormat)
+	if _, closed := closedUntil(v, date); closed {
+		return heading + ": " + digestClosed
+	}
+
+	m, ok := week.Day(date)
+	if !ok {
+		return heading + ": " + menuNotPublished
+	}
+
+	lines := summary(v, m, s, d)
+	if len(lines) == 0 {
+		return heading + ": " + digestClosed
+	}
+
+	return heading + "\n" + strings.Join(lines, "\n")
+}
+
+// digestMessage sends the daily digests due at the time at, or the weekly digests, to the subscribers at v
+func digestMessage(v *venue, mode string, at timeofday.Time) {
+	// The weekly digest covers the coming week, from Monday to Sunday
+	today := now()
+	start, days := today, 1
+	if mode == weeklyDigest {
+		start, days = today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7), 7
+	}
+
+	inTerm := false
+	for i := 0; i < days; i++ {
+		inTerm = inTerm || cfg.calendar.inTerm(start.AddDate(0, 0, i))
+	}
+	if !inTerm {
+		cfg.debug.Printf("%v out of term, skipping %v digest", v.name, mode)
+		return
+	}
+
+	week, err := menus.GetMenus(v.menus)
+	if _, partial := err.(menus.Warnings); err != nil && !partial {
+		cfg.debug.Printf("menu unavailable for digest: %v", err)
+		return
+	}
+
+	// The next week's menu is often published after the weekly digest is sent
+	published := false
+	for i := 0; i < days; i++ {
+		_, ok := week.Day(start.AddDate(0, 0, i))
+		published = published || ok
+	}
+
+	title := ""
+	if len(cfg.venues) > 1 {
+		title = v.display + "\n"
+	}
+
+	num, err := broadcastEach(func(_ string, s subscriber, p preferences) string {
+		if getVenue(p.Venue) != v || s.Digest != mode || (mode == dailyDigest && s.digestAt() != at) {
+			return ""
+		}
+
+		if mode == weeklyDigest && !published {
+			return title + digestNextWeek
+		}
+
+		parts := make([]string, days)
+		for i := range parts {
+			parts[i] = renderDay(v, week, start.AddDate(0, 0, i), s, p.Diet)
+		}
+		return title + strings.Join(parts, "\n\n")
+	})
+	if err != nil {
+		cfg.debug.Println(err)
+	} else {
+		cfg.debug.Printf("timed message send attempt for %v %v digest to %v users", v.name, mode, num)
+	}
+}
+
+// digestHandler sets the subscriber's digest mode from text of the form "daily [time]", "weekly" or "off"
+func digestHandler(sender, text string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		responseMessage(sender, digestUsage, standardQR)
+		return
+	}
+
+	var (
+		mode  string
+		at    *timeofday.Time
+		reply string
+	)
+
+	switch {
+	case fields[0] == dailyDigest && len(fields) <= 2:
+		t := defaultDigestTime
+		if len(fields) == 2 {
+			var err error
+			if t, err = timeofday.Parse(fields[1]); err != nil {
+				responseMessage(sender, digestUsage, standardQR)
+				return
+			}
+		}
+		mode, at, reply = dailyDigest, &t, fmt.Sprintf(digestDaily, t)
+	case fields[0] == weeklyDigest && len(fields) == 1:
+		mode, reply = weeklyDigest, fmt.Sprintf(digestWeekly, weeklyDigestDay, weeklyDigestTime)
+	case fields[0] == "off" && len(fields) == 1:
+		mode, reply = noDigest, digestOff
+	default:
+		responseMessage(sender, digestUsage, standardQR)
+		return
+	}
+
+	s := []byte(sender)
+	err := cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(cfg.userBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", cfg.userBucket)
+		}
+
+		v := b.Get(s)
+		if v == nil {
+			go responseMessage(sender, digestSubscribe, unsubscriptionQR)
+			return nil
+		}
+
+		sub, err := decodeSubscriber(v)
+		if err != nil {
+			return err
+		}
+
+		sub.Digest, sub.DigestAt = mode, at
+		if err = putSubscriber(b, s, sub); err != nil {
+			go responseMessage(sender, unexpected, standardQR)
+			return err
+		}
+		go responseMessage(sender, reply, subscriptionQR)
+
+		return nil
+	})
+
+	if err != nil {
+		cfg.debug.Print(err)
+	}
+}
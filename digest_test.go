@@ -0,0 +1,158 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ratorx/chumenu-go/menus"
+	"github.com/ratorx/chumenu-go/timeofday"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestHandler(t *testing.T) {
+	_, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+
+	digestHandler(testUser, dailyDigest)
+	assert.Equal(t, digestSubscribe, out.last(testUser, 1))
+
+	subscribeHandler(testUser, "")
+	out.to(testUser, 2)
+
+	at := timeofday.New(7, 30)
+	for i, c := range []struct {
+		text     string
+		reply    string
+		expected subscriber
+	}{
+		{"daily", "Daily digest will be sent at 08:00.", subscriber{Digest: dailyDigest, DigestAt: &defaultDigestTime}},
+		{"daily 7:30", "Daily digest will be sent at 07:30.", subscriber{Digest: dailyDigest, DigestAt: &at}},
+		{"daily noon", digestUsage, subscriber{Digest: dailyDigest, DigestAt: &at}},
+		{"weekly", "Weekly digest will be sent on Sundays at 18:00.", subscriber{Digest: weeklyDigest}},
+		{"weekly 7:30", digestUsage, subscriber{Digest: weeklyDigest}},
+		{"fortnightly", digestUsage, subscriber{Digest: weeklyDigest}},
+		{"", digestUsage, subscriber{Digest: weeklyDigest}},
+		{"off", digestOff, subscriber{}},
+	} {
+		digestHandler(testUser, c.text)
+		assert.Equal(t, c.reply, out.last(testUser, i+3), c.text)
+
+		s, ok := storedSubscriber(t, testUser)
+		require.True(t, ok, c.text)
+		assert.Equal(t, c.expected, s, c.text)
+	}
+}
+
+func TestRenderDay(t *testing.T) {
+	v, cleanup := testConfig(t)
+	defer cleanup()
+	cfg.calendar.Closures = []closure{{Start: "2019-06-05", End: "2019-06-05"}}
+
+	week, err := v.menus.Week()
+	require.NoError(t, err)
+	day := func(d int) time.Time { return time.Date(2019, time.June, d, 0, 0, 0, 0, cfg.location) }
+
+	for _, c := range []struct {
+		date     time.Time
+		s        subscriber
+		expected string
+	}{
+		{day(3), subscriber{}, "Mon 3 Jun\n*Lunch*: Monday Lunch\n*Dinner*: Monday Dinner"},
+		{day(3), subscriber{Meals: []string{menus.Dinner}}, "Mon 3 Jun\n*Dinner*: Monday Dinner"},
+		// Dinner is not served on Sunday
+		{day(9), subscriber{}, "Sun 9 Jun\n*Lunch*: Sunday Lunch"},
+		{day(9), subscriber{Meals: []string{menus.Dinner}}, "Sun 9 Jun: Closed"},
+		{day(5), subscriber{}, "Wed 5 Jun: Closed"},
+		{day(11), subscriber{}, "Tue 11 Jun: " + menuNotPublished},
+	} {
+		assert.Equal(t, c.expected, renderDay(v, week, c.date, c.s, noDiet), "%v for %v", c.date, c.s.Meals)
+	}
+}
+
+func TestDigestMessage(t *testing.T) {
+	v, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+
+	other := &venue{name: "other", display: "Other", times: v.times, menus: v.menus}
+	cfg.venues = append(cfg.venues, other)
+
+	early := timeofday.New(7, 30)
+	subscribers := map[string]subscriber{
+		"daily":       {Digest: dailyDigest},
+		"early":       {Digest: dailyDigest, DigestAt: &early},
+		"weekly":      {Digest: weeklyDigest},
+		"meals":       {},
+		"other daily": {Digest: dailyDigest},
+	}
+	require.NoError(t, cfg.db.Update(func(tx *bolt.Tx) error {
+		for sender, s := range subscribers {
+			if err := putSubscriber(tx.Bucket([]byte(cfg.userBucket)), []byte(sender), s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	require.NoError(t, updatePreferences("other daily", func(p *preferences) { p.Venue = other.name }))
+
+	// The menus cover Monday 3rd to Monday 10th June, and the weekly digest is sent the Sunday before
+	setClock(2, weeklyDigestTime)
+	digestMessage(v, weeklyDigest, weeklyDigestTime)
+	setClock(3, defaultDigestTime)
+	digestMessage(v, dailyDigest, defaultDigestTime)
+
+	daily := out.last("daily", 1)
+	assert.Equal(t, "Test\nMon 3 Jun\n*Lunch*: Monday Lunch\n*Dinner*: Monday Dinner", daily)
+
+	weekly := out.last("weekly", 1)
+	assert.True(t, strings.HasPrefix(weekly, "Test\nMon 3 Jun\n"), weekly)
+	assert.Contains(t, weekly, "Sun 9 Jun\n*Lunch*: Sunday Lunch")
+	assert.NotContains(t, weekly, "Sun 2 Jun")
+	assert.NotContains(t, weekly, "Mon 10 Jun")
+
+	// Give any stray messages time to arrive
+	time.Sleep(50 * time.Millisecond)
+	for sender, n := range map[string]int{"early": 0, "meals": 0, "other daily": 0, "daily": 1, "weekly": 1} {
+		assert.Len(t, out.to(sender, 0), n, "Digest sent to %v", sender)
+	}
+}
+
+func TestDigestMessage_Weekly(t *testing.T) {
+	v, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+
+	require.NoError(t, cfg.db.Update(func(tx *bolt.Tx) error {
+		return putSubscriber(tx.Bucket([]byte(cfg.userBucket)), []byte(testUser), subscriber{Digest: weeklyDigest})
+	}))
+
+	// Only Monday 10th of the week after is published
+	setClock(9, weeklyDigestTime)
+	digestMessage(v, weeklyDigest, weeklyDigestTime)
+	weekly := out.last(testUser, 1)
+	assert.True(t, strings.HasPrefix(weekly, "Mon 10 Jun\n*Lunch*: Monday Lunch"), weekly)
+	assert.Contains(t, weekly, "Tue 11 Jun: "+menuNotPublished)
+
+	// Nothing is published for the week after that
+	setClock(16, weeklyDigestTime)
+	digestMessage(v, weeklyDigest, weeklyDigestTime)
+	assert.Equal(t, digestNextWeek, out.last(testUser, 2))
+
+	// Term ends before the coming week
+	cfg.calendar.Terms = []term{{Start: "2019-04-23", End: "2019-06-14"}}
+	setClock(16, weeklyDigestTime)
+	digestMessage(v, weeklyDigest, weeklyDigestTime)
+	setClock(9, weeklyDigestTime)
+	digestMessage(v, weeklyDigest, weeklyDigestTime)
+	assert.Len(t, out.to(testUser, 3), 3, "Weekly digest not sent in term")
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, out.to(testUser, 0), 3, "Weekly digest sent out of term")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ratorx/chumenu-go/menus"
+	"github.com/ratorx/chumenu-go/timeofday"
+)
+
+// maxCatchUp is the longest gap between ticks for which missed minutes are checked
+const maxCatchUp = 10 * time.Minute
+
+// dispatcher decides when the timed messages for each meal and the digests are due, by checking the wall clock every minute.
+// Times are compared in cfg.location, so messages follow the clocks when they change.
+type dispatcher struct {
+	venues []*venue
+	plan   func() plan // times chosen by subscribers
+	meal   func(v *venue, meal string, lead time.Duration)
+	digest func(v *venue, mode string, at timeofday.Time)
+	last   time.Time       // last minute checked
+	sent   map[string]bool // messages sent, so a minute repeated when the clocks go back is only handled once
+}
+
+func newDispatcher(venues []*venue, plan func() plan, meal func(*venue, string, time.Duration), digest func(*venue, string, timeofday.Time)) *dispatcher {
+	return &dispatcher{venues: venues, plan: plan, meal: meal, digest: digest, sent: make(map[string]bool)}
+}
+
+// tick sends the messages due in the minutes since the last tick, up to and including the minute of t
+func (d *dispatcher) tick(t time.Time) {
+	t = t.Truncate(time.Minute)
+	if d.last.IsZero() || t.Sub(d.last) > maxCatchUp {
+		d.last = t.Add(-time.Minute)
+	}
+
+	p := d.plan()
+	for m := d.last.Add(time.Minute); !m.After(t); m = m.Add(time.Minute) {
+		d.due(m.In(cfg.location), p)
+	}
+	d.last = t
+
+	// Forget messages sent before today
+	today := menus.DateKey(t.In(cfg.location))
+	for key := range d.sent {
+		if key[:len(today)] < today {
+			delete(d.sent, key)
+		}
+	}
+}
+
+// due sends the messages which are due at the wall clock minute of t
+func (d *dispatcher) due(t time.Time, p plan) {
+	tod := timeofday.Of(t)
+	for _, v := range d.venues {
+		if p.digest[tod] && d.once(fmt.Sprintf("%v/%v/%v/%v", menus.DateKey(t), v.name, dailyDigest, tod)) {
+			d.digest(v, dailyDigest, tod)
+		}
+
+		if t.Weekday() == weeklyDigestDay && tod == weeklyDigestTime && d.once(fmt.Sprintf("%v/%v/%v", menus.DateKey(t), v.name, weeklyDigest)) {
+			d.digest(v, weeklyDigest, tod)
+		}
+
+		for _, meal := range v.meals() {
+			mt, ok := v.mealTime(meal, t)
+			if !ok {
+				continue
+			}
+
+			for lead := range p.leads {
+				if mt.Start.Add(-lead) == tod && d.once(fmt.Sprintf("%v/%v/%v/%v", menus.DateKey(t), v.name, meal, lead)) {
+					d.meal(v, meal, lead)
+				}
+			}
+		}
+	}
+}
+
+// once reports whether the message identified by key has not been sent before, and records it as sent
+func (d *dispatcher) once(key string) bool {
+	if d.sent[key] {
+		return false
+	}
+
+	d.sent[key] = true
+	return true
+}
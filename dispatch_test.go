@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ratorx/chumenu-go/menus"
+	"github.com/ratorx/chumenu-go/timeofday"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLocation(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	cfg.location = london
+}
+
+func defaultPlan() plan {
+	return plan{leads: map[time.Duration]bool{interval: true}}
+}
+
+func noDigests(*venue, string, timeofday.Time) {}
+
+// Ticks every minute from start until end, and returns the times at which messages were sent
+func runDispatcher(v *venue, start, end time.Time) []time.Time {
+	var sent []time.Time
+	var current time.Time
+
+	d := newDispatcher([]*venue{v}, defaultPlan, func(*venue, string, time.Duration) { sent = append(sent, current.UTC()) }, noDigests)
+	for current = start; current.Before(end); current = current.Add(time.Minute) {
+		d.tick(current)
+	}
+
+	return sent
+}
+
+func TestDispatcher_ClocksForward(t *testing.T) {
+	testLocation(t)
+	v := &venue{name: "test", times: map[string]schedule{menus.Lunch: everyDay(mealTime{timeofday.New(12, 15), timeofday.New(13, 45)})}}
+
+	// The clocks go forward at 01:00 UTC on Sunday 31st March 2019
+	sent := runDispatcher(v, time.Date(2019, time.March, 30, 0, 0, 0, 0, time.UTC), time.Date(2019, time.April, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, []time.Time{
+		time.Date(2019, time.March, 30, 11, 30, 0, 0, time.UTC),
+		time.Date(2019, time.March, 31, 10, 30, 0, 0, time.UTC),
+	}, sent)
+}
+
+func TestDispatcher_ClocksBack(t *testing.T) {
+	testLocation(t)
+	// 01:00 happens twice when the clocks go back at 02:00 BST on Sunday 28th October 2018
+	v := &venue{name: "test", times: map[string]schedule{menus.Breakfast: everyDay(mealTime{timeofday.New(1, 45), timeofday.New(3, 0)})}}
+
+	sent := runDispatcher(v, time.Date(2018, time.October, 27, 23, 0, 0, 0, time.UTC), time.Date(2018, time.October, 28, 3, 0, 0, 0, time.UTC))
+	assert.Equal(t, []time.Time{time.Date(2018, time.October, 28, 0, 0, 0, 0, time.UTC)}, sent, "Message sent twice in the repeated hour")
+}
+
+func TestDispatcher_CatchUp(t *testing.T) {
+	testLocation(t)
+	v := &venue{name: "test", times: map[string]schedule{menus.Lunch: everyDay(mealTime{timeofday.New(12, 15), timeofday.New(13, 45)})}}
+
+	var sent int
+	d := newDispatcher([]*venue{v}, defaultPlan, func(*venue, string, time.Duration) { sent++ }, noDigests)
+	d.tick(time.Date(2018, time.December, 3, 11, 28, 30, 0, time.UTC))
+	d.tick(time.Date(2018, time.December, 3, 11, 31, 10, 0, time.UTC))
+	assert.Equal(t, 1, sent, "Message missed when a tick is late")
+
+	d.tick(time.Date(2018, time.December, 3, 11, 32, 0, 0, time.UTC))
+	assert.Equal(t, 1, sent, "Message sent twice")
+}
+
+func TestDispatcher_Leads(t *testing.T) {
+	testLocation(t)
+	v := &venue{name: "test", times: map[string]schedule{menus.Lunch: everyDay(mealTime{timeofday.New(12, 15), timeofday.New(13, 45)})}}
+
+	sent := make(map[time.Duration]time.Time)
+	var current time.Time
+	leads := func() plan { return plan{leads: map[time.Duration]bool{20 * time.Minute: true, 2 * time.Hour: true}} }
+	d := newDispatcher([]*venue{v}, leads, func(_ *venue, _ string, lead time.Duration) { sent[lead] = current }, noDigests)
+
+	start := time.Date(2018, time.December, 3, 9, 0, 0, 0, time.UTC)
+	for current = start; current.Before(start.Add(5 * time.Hour)); current = current.Add(time.Minute) {
+		d.tick(current)
+	}
+
+	assert.Equal(t, map[time.Duration]time.Time{
+		20 * time.Minute: time.Date(2018, time.December, 3, 11, 55, 0, 0, time.UTC),
+		2 * time.Hour:    time.Date(2018, time.December, 3, 10, 15, 0, 0, time.UTC),
+	}, sent)
+}
+
+func TestDispatcher_Digests(t *testing.T) {
+	testLocation(t)
+	v := &venue{name: "test"}
+
+	type digest struct {
+		mode string
+		at   time.Time
+	}
+	var sent []digest
+	var current time.Time
+	p := func() plan { return plan{digest: map[timeofday.Time]bool{timeofday.New(7, 30): true}} }
+	d := newDispatcher([]*venue{v}, p, nil, func(_ *venue, mode string, _ timeofday.Time) { sent = append(sent, digest{mode, current}) })
+
+	// Saturday 1st to Monday 3rd December 2018
+	start := time.Date(2018, time.December, 1, 0, 0, 0, 0, time.UTC)
+	for current = start; current.Before(start.AddDate(0, 0, 3)); current = current.Add(time.Minute) {
+		d.tick(current)
+	}
+
+	assert.Equal(t, []digest{
+		{dailyDigest, time.Date(2018, time.December, 1, 7, 30, 0, 0, time.UTC)},
+		{dailyDigest, time.Date(2018, time.December, 2, 7, 30, 0, 0, time.UTC)},
+		{weeklyDigest, time.Date(2018, time.December, 2, 18, 0, 0, 0, time.UTC)},
+		{dailyDigest, time.Date(2018, time.December, 3, 7, 30, 0, 0, time.UTC)},
+	}, sent)
+}
@@ -85,7 +85,7 @@ func (w *Webhook) verify(response http.ResponseWriter, request *http.Request) {
 	case urlQueries.Get("hub.verify_token") != w.VerifyToken:
 		http.Error(response, "invalid hub.verify_token", http.StatusUnauthorized)
 	default:
-		fmt.Fprintf(response, urlQueries.Get("hub.challenge"))
+		fmt.Fprint(response, urlQueries.Get("hub.challenge"))
 		w.Debug.Print("Webhook verified.")
 	}
 }
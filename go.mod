@@ -1,9 +1,10 @@
 module github.com/ratorx/chumenu-go
 
+go 1.15
+
 require (
 	github.com/boltdb/bolt v1.3.1
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/jasonlvhit/gocron v0.0.0-20180312192515-54194c9749d4
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/testify v1.2.2
 	github.com/yhat/scrape v0.0.0-20161128144610-24b7890b0945
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/boltdb/bolt"
 	"github.com/ratorx/chumenu-go/facebook"
 	"github.com/ratorx/chumenu-go/menus"
+	"github.com/ratorx/chumenu-go/timeofday"
 )
 
 type eventHandler struct {
@@ -16,12 +18,24 @@ type eventHandler struct {
 
 // Defined keywords
 const (
-	lunch       = "lunch"
-	dinner      = "dinner"
-	times       = "times"
-	help        = "help"
-	subscribe   = "subscribe"
-	unsubscribe = "unsubscribe"
+	breakfast    = "breakfast"
+	brunch       = "brunch"
+	lunch        = "lunch"
+	dinner       = "dinner"
+	formal       = "formal"
+	times        = "times"
+	help         = "help"
+	subscribe    = "subscribe"
+	unsubscribe  = "unsubscribe"
+	notify       = "notify"
+	digest       = "digest"
+	history      = "history"
+	find         = "find"
+	watch        = "watch"
+	unwatch      = "unwatch"
+	watches      = "watches"
+	dietKeyword  = "diet"
+	venueKeyword = "venue"
 )
 
 // Common quick replies
@@ -44,9 +58,31 @@ const (
 	unsubscribeFail    = "Not currently subscribed."
 
 	// Other defaults
-	helpMessage  = "Available commands:\n*subscribe* - Receive regular menu updates\n*unsubscribe* - Unsubscribe from menu updates\n*lunch* - Get the next lunch menu\n*dinner* - Get the next the dinner menu\n*times* - Get lunch and dinner times"
+	helpMessage  = "Available commands:\n*subscribe* - Receive regular menu updates\n*unsubscribe* - Unsubscribe from menu updates\n*subscribe <meal>*, *unsubscribe <meal>* - Choose which meals to receive menus for\n*notify <minutes>* - Choose how long before each meal menus are sent\n*digest <daily [time]|weekly|off>* - Get one message a day or week instead\n*lunch* - Get the next lunch menu\n*dinner* - Get the next the dinner menu\n*breakfast*, *brunch*, *formal* - Get the next menu for other meals\n*times* - Get meal times\n*history <date>* - Get the menu served on a past date\n*find <dish>* - Find when a dish is next served\n*watch <dish>* - Get a message when a dish is on the menu\n*unwatch <dish>* - Stop watching for a dish\n*watches* - List watched dishes\n*diet <vegetarian|vegan|none>* - Only show items suitable for a diet\n*venue <name>* - Choose which dining hall to get menus for"
 	unrecognised = "Command not recognised. Type *help* for a list of available commands."
 	unexpected   = "Unexpected Error. Will fix ASAP."
+	staleMenu    = "Menu may be out of date (last updated %s)."
+	noMenu       = "Menu currently unavailable."
+
+	// Menu error messages
+	menuSiteDown      = "The menu site is down, so the menu is unavailable. Try again later."
+	menuLayoutChanged = "The menu site has changed and the menu could not be read. Will fix ASAP."
+	menuNotPublished  = "The menu has not been published yet."
+	mealInvalid       = "Meal not recognised. Choose from breakfast, brunch, lunch, dinner or formal."
+	mealNotServed     = "%s is not served at %s in the next %v days."
+
+	// History messages
+	historyInvalid = "Date not recognised. Try e.g. *history monday* or *history 3/12*."
+	historyMissing = "No menu archived for %s."
+
+	// Find messages
+	findNone     = "%q is not on any upcoming menus."
+	findPastNone = "%q has not been served before."
+	maxFindPast  = 3 // past occurrences listed
+
+	// Job messages
+	jobsNone   = "No jobs scheduled."
+	jobsFormat = "Mon 2 Jan 15:04"
 )
 
 func responseMessage(r string, text string, qr []facebook.QuickReply) {
@@ -61,9 +97,24 @@ func subscriptionMessage(r string, text string, qr []facebook.QuickReply) {
 	}
 }
 
-func subscribeHandler(sender string) {
+// subscribeHandler subscribes sender to messages for every meal, or adds meal to their subscription if it is not empty
+func subscribeHandler(sender, meal string) {
 	s := []byte(sender)
 
+	// A subscription to a meal the venue doesn't serve would never send anything
+	if meal != "" {
+		v := getVenue(getPreferences(sender).Venue)
+		served := false
+		for _, m := range v.meals() {
+			served = served || m == meal
+		}
+
+		if !served {
+			responseMessage(sender, fmt.Sprintf(subscribeMealNotServed, meal, v.display), standardQR)
+			return
+		}
+	}
+
 	err := cfg.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(cfg.userBucket))
 		if b == nil {
@@ -71,17 +122,41 @@ func subscribeHandler(sender string) {
 		}
 
 		v := b.Get(s)
-		if v != nil {
+		if meal == "" && v != nil {
 			go responseMessage(sender, subscribeFail, subscriptionQR)
 			return nil
 		}
 
-		err := b.Put(s, []byte{})
+		var sub subscriber
+		if meal != "" {
+			sub.Meals = []string{meal}
+		}
+
+		if v != nil {
+			existing, err := decodeSubscriber(v)
+			if err != nil {
+				return err
+			}
+
+			if existing.wants(meal) {
+				go responseMessage(sender, fmt.Sprintf(subscribeMealFail, strings.ToLower(meal)), subscriptionQR)
+				return nil
+			}
+			sub = existing
+			sub.Meals = append(sub.Meals, meal)
+		}
+
+		err := putSubscriber(b, s, sub)
 		if err != nil {
 			go responseMessage(sender, unexpected, standardQR)
 			return err
 		}
-		go responseMessage(sender, subscribeSuccess, subscriptionQR)
+
+		if meal == "" {
+			go responseMessage(sender, subscribeSuccess, subscriptionQR)
+		} else {
+			go responseMessage(sender, fmt.Sprintf(subscribeMealSuccess, strings.ToLower(meal)), subscriptionQR)
+		}
 
 		return nil
 	})
@@ -92,8 +167,11 @@ func subscribeHandler(sender string) {
 
 }
 
-func unsubscribeHandler(sender string) {
+// unsubscribeHandler unsubscribes sender from all messages, or removes meal from their subscription if it is not empty.
+// Subscribers left without any meals are unsubscribed.
+func unsubscribeHandler(sender, meal string) {
 	s := []byte(sender)
+	served := getVenue(getPreferences(sender).Venue).meals()
 
 	err := cfg.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(cfg.userBucket))
@@ -107,6 +185,40 @@ func unsubscribeHandler(sender string) {
 			return nil
 		}
 
+		if meal != "" {
+			sub, err := decodeSubscriber(v)
+			if err != nil {
+				return err
+			}
+
+			if !sub.wants(meal) {
+				go responseMessage(sender, fmt.Sprintf(unsubscribeMealFail, strings.ToLower(meal)), subscriptionQR)
+				return nil
+			}
+
+			// Every meal is replaced by the meals served at the subscriber's venue
+			meals := sub.Meals
+			if len(meals) == 0 {
+				meals = served
+			}
+
+			sub.Meals = nil
+			for _, m := range meals {
+				if m != meal {
+					sub.Meals = append(sub.Meals, m)
+				}
+			}
+
+			if len(sub.Meals) != 0 {
+				if err = putSubscriber(b, s, sub); err != nil {
+					go responseMessage(sender, unexpected, standardQR)
+					return err
+				}
+				go responseMessage(sender, fmt.Sprintf(unsubscribeMealSuccess, strings.ToLower(meal)), subscriptionQR)
+				return nil
+			}
+		}
+
 		err := b.Delete(s)
 		if err != nil {
 			go responseMessage(sender, unexpected, standardQR)
@@ -122,101 +234,382 @@ func unsubscribeHandler(sender string) {
 	}
 }
 
-func getMenu(isLunch bool) (string, menus.Meal) {
-	currentTime := time.Now()
-	block, _ := menus.GetData(currentTime.Weekday())
-	currentHM := hourMinute{uint8(currentTime.Hour()), uint8(currentTime.Minute())}
+func refreshMenus(v *venue) {
+	// A refresh caught up by the scheduler can overlap the one at startup
+	v.refreshing.Lock()
+	defer v.refreshing.Unlock()
+
+	old, _ := v.menus.Week()
+	changes, err := v.menus.Refresh()
+	if _, partial := err.(menus.Warnings); partial {
+		cfg.debug.Printf("%v menu refreshed with warnings: %v", v.name, err)
+	} else if err != nil {
+		cfg.debug.Printf("%v menu refresh failed: %v", v.name, err)
+	}
+
+	if week, err := v.menus.Week(); err == nil { // nolint: vetshadow
+		if err = v.archive.Put(week); err != nil {
+			cfg.debug.Printf("%v menu archive failed: %v", v.name, err)
+		}
+		watchAlerts(v, old, week)
+	}
+
+	if len(changes) != 0 {
+		changeMessage(v, changes)
+	}
+
+	healthCheck(v, err)
+}
+
+// healthCheck sends the admin a diagnostic message if the last refresh indicates the venue's scraper is broken
+func healthCheck(v *venue, err error) {
+	var week menus.Week
+	if _, partial := err.(menus.Warnings); err == nil || partial {
+		week, _ = v.menus.Week()
+	}
+
+	if message, alert := v.health.Check(week, err, now()); alert {
+		adminMessage(v.display + ": " + message)
+	}
+}
+
+func adminMessage(text string) {
+	if cfg.admin == "" {
+		cfg.debug.Printf("no admin user for message: %v", text)
+		return
+	}
+
+	if err := cfg.sendClient.SendMessage(cfg.admin, text, facebook.Subscription, nil); err != nil {
+		cfg.debug.Print(err)
+	}
+}
+
+// errNotServed is returned by getMenu if the meal is not served soon enough to show a menu
+var errNotServed = errors.New("meal not served")
+
+// getMenu returns the next menu for the named meal at v, along with a prefix naming the meal and day
+func getMenu(v *venue, name string) (string, menus.Meal, error) {
+	currentTime := now()
+	currentTOD := timeofday.Of(currentTime)
+
+	// Once today's meal has ended (or if there is none), the next one is shown
+	from := currentTime
+	if mt, ok := v.mealTime(name, currentTime); !ok || currentTOD.After(mt.End) {
+		from = from.AddDate(0, 0, 1)
+	}
+
+	date, _, ok := v.times[name].next(from)
+	if !ok {
+		return "", nil, errNotServed
+	}
 
 	var prefix string
-	var meal menus.Meal
+	switch menus.DateKey(date) {
+	case menus.DateKey(currentTime):
+		prefix = "Today's " + name + ":"
+	case menus.DateKey(currentTime.AddDate(0, 0, 1)):
+		prefix = "Tomorrow's " + name + ":"
+	default:
+		prefix = date.Weekday().String() + "'s " + name + ":"
+	}
 
-	if isLunch {
-		if currentHM.IsAfter(lunchTime.End) {
-			prefix = "Tomorrow's Lunch:"
-			meal = block.Next.Lunch
-		} else {
-			prefix = "Today's Lunch:"
-			meal = block.Current.Lunch
-		}
-	} else {
-		if currentHM.IsAfter(dinnerTime.End) {
-			prefix = "Tomorrow's Dinner:"
-			meal = block.Next.Dinner
-		} else {
-			prefix = "Today's Dinner:"
-			meal = block.Current.Dinner
+	// Only name the venue when there is a choice
+	if len(cfg.venues) > 1 {
+		prefix = v.display + " - " + prefix
+	}
+
+	if until, closed := closedUntil(v, date); closed {
+		return prefix, nil, &closedError{venue: v, until: until}
+	}
+
+	menu, err := menus.GetDay(v.menus, date)
+	if _, partial := err.(menus.Warnings); partial {
+		// The day was parsed despite problems elsewhere in the table
+		err = nil
+	}
+
+	meal, _ := menu.Meal(name)
+	return prefix, meal, err
+}
+
+// menuError returns the message explaining why a menu at v is unavailable
+func menuError(v *venue, err error) string {
+	// A missing day is explained by the last refresh failing, if it did
+	if _, ok := err.(*menus.DayMissingError); ok {
+		if refreshErr := v.menus.Err(); refreshErr != nil {
+			if _, partial := refreshErr.(menus.Warnings); !partial {
+				err = refreshErr
+			}
 		}
 	}
 
-	return prefix, meal
+	switch err.(type) {
+	case *closedError:
+		return err.Error()
+	case *menus.NetworkError:
+		return menuSiteDown
+	case *menus.LayoutError:
+		return menuLayoutChanged
+	case *menus.DayMissingError, *menus.EmptyTableError:
+		return menuNotPublished
+	}
+
+	return noMenu
 }
 
-func menuMessage(r string, isLunch bool) {
-	prefix, meal := getMenu(isLunch)
-	responseMessage(r, prefix+"\n"+meal.String(), standardQR)
+func menuMessage(r string, name string) {
+	p := getPreferences(r)
+	v := getVenue(p.Venue)
+
+	prefix, meal, err := getMenu(v, name)
+	if err == errNotServed {
+		responseMessage(r, fmt.Sprintf(mealNotServed, name, v.display, maxLookahead), standardQR)
+		return
+	} else if err != nil {
+		cfg.debug.Print(err)
+		responseMessage(r, menuError(v, err), standardQR)
+		return
+	}
+
+	text := p.Diet.render(prefix, meal)
+	if v.menus.Stale() {
+		text += "\n\n" + fmt.Sprintf(staleMenu, v.menus.Updated().Format("Mon 2 Jan 15:04"))
+	}
+
+	responseMessage(r, text, standardQR)
 }
 
-func timedMessage(isLunch, forceSend bool) {
-	prefix, meal := getMenu(isLunch)
+func timesMessage(r string) {
+	v := getVenue(getPreferences(r).Venue)
 
-	if !forceSend && len(meal) == 0 {
-		cfg.debug.Print("data unavailable for timed message")
+	var lines []string
+	for _, name := range v.meals() {
+		lines = append(lines, fmt.Sprintf("%s Time:\n%s\n", name, v.times[name].describe(now())))
+	}
+
+	if closures := upcomingClosures(v, now()); len(closures) != 0 {
+		lines = append(lines, closuresTitle)
+		for i, c := range closures {
+			if i == maxClosures {
+				break
+			}
+			lines = append(lines, c.String())
+		}
+	}
+
+	responseMessage(r, strings.Join(lines, "\n"), standardQR)
+}
+
+func historyMessage(r string, text string) {
+	date, err := menus.ParseDate(text, now())
+	if err != nil {
+		responseMessage(r, historyInvalid, standardQR)
+		return
+	}
+
+	day := date.Format("Mon 2 Jan 2006")
+	m, err := getVenue(getPreferences(r).Venue).archive.Day(date)
+	if err == menus.ErrNotArchived {
+		responseMessage(r, fmt.Sprintf(historyMissing, day), standardQR)
+		return
+	} else if err != nil {
+		cfg.debug.Print(err)
+		responseMessage(r, unexpected, standardQR)
 		return
 	}
 
-	menu := prefix + "\n" + meal.String()
+	responseMessage(r, "Menu for "+day+":"+m.String(), standardQR)
+}
 
-	var num uint
-	var mealName string
+func formatMatch(m menus.Match) string {
+	return fmt.Sprintf("%s %s: %s", m.Date.Format("Mon 2 Jan"), m.Meal, m.Item)
+}
+
+func findMessage(r string, query string) {
+	v := getVenue(getPreferences(r).Venue)
+	today := now()
+	y, mo, d := today.Date()
+	start := time.Date(y, mo, d, 0, 0, 0, 0, today.Location())
+
+	var upcoming []menus.Match
+	if week, err := v.menus.Week(); err == nil {
+		for _, m := range menus.Search(week, query) {
+			if !m.Date.Before(start) {
+				upcoming = append(upcoming, m)
+			}
+		}
+	}
+
+	past, err := v.archive.Range(time.Time{}, start.AddDate(0, 0, -1))
+	if err != nil {
+		cfg.debug.Print(err)
+	}
+	previous := menus.Search(past, query)
+
+	var lines []string
+	if len(upcoming) == 0 {
+		lines = append(lines, fmt.Sprintf(findNone, query))
+	} else {
+		lines = append(lines, "Next served:", formatMatch(upcoming[0]))
+		if len(upcoming) > 1 {
+			lines = append(lines, "", "Also upcoming:")
+			for _, m := range upcoming[1:] {
+				lines = append(lines, formatMatch(m))
+			}
+		}
+	}
 
-	if isLunch {
-		mealName = "lunch"
+	lines = append(lines, "")
+	if len(previous) == 0 {
+		lines = append(lines, fmt.Sprintf(findPastNone, query))
 	} else {
-		mealName = "dinner"
+		lines = append(lines, "Previously served:")
+		// Most recent first
+		for i := len(previous) - 1; i >= 0 && i >= len(previous)-maxFindPast; i-- {
+			lines = append(lines, formatMatch(previous[i]))
+		}
 	}
 
+	responseMessage(r, strings.Join(lines, "\n"), standardQR)
+}
+
+// broadcast sends message to every subscriber and returns the number of subscribers
+func broadcast(message string) (uint, error) {
+	return broadcastEach(func(string, subscriber, preferences) string { return message })
+}
+
+// broadcastEach sends every subscriber the message returned by render for their subscription and preferences.
+// Subscribers for which render returns an empty message are skipped. The number of messages sent is returned.
+func broadcastEach(render func(sender string, s subscriber, p preferences) string) (uint, error) {
+	var num uint
+
 	err := cfg.db.View(func(tx *bolt.Tx) error { // nolint: errcheck
 		// Assume bucket exists and has keys
 		b := tx.Bucket([]byte(cfg.userBucket))
+		prefs := tx.Bucket([]byte(preferenceBucket))
 
-		if b == nil {
-			return fmt.Errorf("database corrupted: bucket %v not found", cfg.userBucket)
+		if b == nil || prefs == nil {
+			return fmt.Errorf("database corrupted: bucket %v or %v not found", cfg.userBucket, preferenceBucket)
 		}
 
 		b.ForEach(func(k, v []byte) error { // nolint: errcheck
-			go subscriptionMessage(string(k), menu, subscriptionQR)
-			num++
+			// A corrupt record is skipped, rather than read as a subscription to every meal
+			s, err := decodeSubscriber(v)
+			if err != nil {
+				cfg.debug.Print(err)
+				return nil
+			}
+
+			p, err := loadPreferences(prefs, k)
+			if err != nil {
+				cfg.debug.Print(err)
+			}
+
+			if message := render(string(k), s, p); message != "" {
+				go subscriptionMessage(string(k), message, subscriptionQR)
+				num++
+			}
 			return nil
 		})
 		return nil
 	})
 
+	return num, err
+}
+
+// timedMessage sends the menu for the named meal to the subscribers at v who receive it with the given notice
+func timedMessage(v *venue, name string, lead time.Duration, forceSend bool) {
+	today := now()
+	if _, closed := closedUntil(v, today); !forceSend && (closed || !cfg.calendar.inTerm(today)) {
+		cfg.debug.Printf("%v closed or out of term, skipping timed message", v.name)
+		return
+	}
+
+	if !forceSend && v.menus.Stale() {
+		cfg.debug.Printf("%v menus last updated %v, skipping timed message", v.name, v.menus.Updated())
+		return
+	}
+
+	prefix, meal, err := getMenu(v, name)
+	if err != nil {
+		cfg.debug.Printf("menu unavailable for timed message: %v", err)
+		return
+	}
+
+	if !forceSend && len(meal) == 0 {
+		cfg.debug.Print("data unavailable for timed message")
+		return
+	}
+
+	num, err := broadcastEach(func(_ string, s subscriber, p preferences) string {
+		if getVenue(p.Venue) != v || s.Digest != noDigest || !s.wants(name) || s.lead() != lead {
+			return ""
+		}
+		return p.Diet.render(prefix, meal)
+	})
 	if err != nil {
 		cfg.debug.Println(err)
 	} else {
-		cfg.debug.Printf("timed message send attempt for %v message to %v users", mealName, num)
+		cfg.debug.Printf("timed message send attempt for %v %v message (%v notice) to %v users", v.name, strings.ToLower(name), lead, num)
 	}
 }
 
-func announceMessage(message string) {
-	var num uint
+// changeMessage notifies subscribers at v of changes to today's meals which were made after the timed message was sent
+func changeMessage(v *venue, changes []menus.Change) {
+	currentTime := now()
+	currentTOD := timeofday.Of(currentTime)
 
-	err := cfg.db.View(func(tx *bolt.Tx) error { // nolint: errcheck
-		// Assume bucket exists and has keys
-		b := tx.Bucket([]byte(cfg.userBucket))
+	type update struct {
+		change menus.Change
+		time   mealTime
+	}
 
-		if b == nil {
-			return fmt.Errorf("database corrupted: bucket %v not found", cfg.userBucket)
+	// Changes after the meal are irrelevant
+	var updates []update
+	for _, c := range changes {
+		if menus.DateKey(c.Date) != menus.DateKey(currentTime) {
+			continue
 		}
 
-		b.ForEach(func(k, v []byte) error { // nolint: errcheck
-			go subscriptionMessage(string(k), message, subscriptionQR)
-			num++
-			return nil
-		})
-		return nil
+		if mt, ok := v.mealTime(c.Meal, currentTime); ok && !currentTOD.After(mt.End) {
+			updates = append(updates, update{c, mt})
+		}
+	}
+
+	if len(updates) == 0 {
+		return
+	}
+
+	num, err := broadcastEach(func(_ string, s subscriber, p preferences) string {
+		if getVenue(p.Venue) != v || s.Digest != noDigest {
+			return ""
+		}
+
+		// Changes before the subscriber's timed message are included in it
+		var lines []string
+		for _, u := range updates {
+			if s.wants(u.change.Meal) && currentTOD.After(u.time.Start.Add(-s.lead())) {
+				lines = append(lines, u.change.String())
+			}
+		}
+
+		if len(lines) == 0 {
+			return ""
+		}
+		if len(cfg.venues) > 1 {
+			lines = append([]string{v.display + ":"}, lines...)
+		}
+		return strings.Join(lines, "\n")
 	})
+	if err != nil {
+		cfg.debug.Println(err)
+	} else {
+		cfg.debug.Printf("timed message send attempt for %v menu update to %v users", v.name, num)
+	}
+}
 
+func announceMessage(message string) {
+	num, err := broadcast(message)
 	if err != nil {
 		cfg.debug.Println(err)
 	} else {
@@ -224,6 +617,26 @@ func announceMessage(message string) {
 	}
 }
 
+// jobsMessage sends the admin the next planned run of each scheduled job
+func jobsMessage(sender string) {
+	if sender != cfg.admin {
+		responseMessage(sender, adminOnly, standardQR)
+		return
+	}
+
+	planned := cfg.scheduler.Planned()
+	if len(planned) == 0 {
+		responseMessage(sender, jobsNone, standardQR)
+		return
+	}
+
+	lines := make([]string, len(planned))
+	for i, r := range planned {
+		lines[i] = fmt.Sprintf("*%s*: %s", r.Job, r.At.In(cfg.location).Format(jobsFormat))
+	}
+	responseMessage(sender, strings.Join(lines, "\n"), standardQR)
+}
+
 func defaultHandler(sender, text string) {
 	cfg.debug.Printf("unrecognised command: %v", text)
 	responseMessage(sender, unrecognised, defQR)
@@ -233,9 +646,9 @@ func defaultHandler(sender, text string) {
 func (e eventHandler) HandleEvent(m []facebook.MessagingEvent) {
 	for i := range m {
 		r := m[i].Sender.String()
-		text := strings.TrimSpace(m[i].Message.Text)
-		text = strings.Trim(text, "*_`")
-		text = strings.ToLower(text)
+		original := strings.TrimSpace(m[i].Message.Text)
+		original = strings.Trim(original, "*_`")
+		text := strings.ToLower(original)
 
 		if !strings.HasPrefix(text, e.commandPrefix) {
 			defaultHandler(r, text)
@@ -244,25 +657,97 @@ func (e eventHandler) HandleEvent(m []facebook.MessagingEvent) {
 
 		text = strings.TrimPrefix(text, e.commandPrefix)
 
-		// Parse annouce message
+		// Parse announce message, keeping the case of the original
 		if strings.HasPrefix(text, "announce ") {
-			announceMessage(strings.TrimPrefix(text, "announce "))
+			announceHandler(r, strings.TrimSpace(original[len(e.commandPrefix)+len("announce "):]))
+			continue
+		}
+
+		// Parse history date
+		if strings.HasPrefix(text, history+" ") {
+			historyMessage(r, strings.TrimPrefix(text, history+" "))
+			continue
+		}
+
+		// Parse find query
+		if strings.HasPrefix(text, find+" ") {
+			findMessage(r, strings.TrimSpace(strings.TrimPrefix(text, find+" ")))
+			continue
+		}
+
+		// Parse watch terms
+		if strings.HasPrefix(text, watch+" ") {
+			watchHandler(r, strings.TrimSpace(strings.TrimPrefix(text, watch+" ")))
+			continue
+		} else if strings.HasPrefix(text, unwatch+" ") {
+			unwatchHandler(r, strings.TrimSpace(strings.TrimPrefix(text, unwatch+" ")))
+			continue
+		}
+
+		// Parse closures
+		if strings.HasPrefix(text, "close ") {
+			closeHandler(r, strings.TrimSpace(strings.TrimPrefix(text, "close ")))
+			continue
+		} else if strings.HasPrefix(text, "reopen ") {
+			reopenHandler(r, strings.TrimSpace(strings.TrimPrefix(text, "reopen ")))
+			continue
+		}
+
+		// Parse venue
+		if text == venueKeyword || strings.HasPrefix(text, venueKeyword+" ") {
+			venueHandler(r, strings.TrimSpace(strings.TrimPrefix(text, venueKeyword)))
+			continue
+		}
+
+		// Parse diet
+		if text == dietKeyword || strings.HasPrefix(text, dietKeyword+" ") {
+			dietHandler(r, strings.TrimSpace(strings.TrimPrefix(text, dietKeyword)))
+			continue
+		}
+
+		// Parse subscription meals and notice
+		if strings.HasPrefix(text, subscribe+" ") || strings.HasPrefix(text, unsubscribe+" ") {
+			words := strings.SplitN(text, " ", 2)
+			meal, ok := menus.MealName(words[1])
+			if !ok {
+				responseMessage(r, mealInvalid, standardQR)
+			} else if words[0] == subscribe {
+				subscribeHandler(r, meal)
+			} else {
+				unsubscribeHandler(r, meal)
+			}
+			continue
+		} else if strings.HasPrefix(text, digest+" ") {
+			digestHandler(r, strings.TrimPrefix(text, digest+" "))
+			continue
+		} else if strings.HasPrefix(text, notify+" ") {
+			notifyHandler(r, strings.TrimSpace(strings.TrimPrefix(text, notify+" ")))
 			continue
 		}
 
 		switch text {
 		case "subscribe", "s":
-			subscribeHandler(r)
+			subscribeHandler(r, "")
 		case "unsubscribe", "u":
-			unsubscribeHandler(r)
+			unsubscribeHandler(r, "")
 		case "help", "h":
 			responseMessage(r, helpMessage, helpQR)
 		case "times", "t":
-			responseMessage(r, fmt.Sprintf("Lunch Time:\n%s\n\nDinner Time:\n%s\n", lunchTime, dinnerTime), standardQR)
+			timesMessage(r)
+		case "watches", "w":
+			watchesMessage(r)
+		case "jobs":
+			jobsMessage(r)
+		case "breakfast", "b":
+			menuMessage(r, menus.Breakfast)
+		case "brunch":
+			menuMessage(r, menus.Brunch)
 		case "lunch", "l":
-			menuMessage(r, true)
+			menuMessage(r, menus.Lunch)
 		case "dinner", "d":
-			menuMessage(r, false)
+			menuMessage(r, menus.Dinner)
+		case "formal", "f":
+			menuMessage(r, menus.Formal)
 		default:
 			defaultHandler(r, text)
 		}
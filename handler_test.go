@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ratorx/chumenu-go/facebook"
+	"github.com/ratorx/chumenu-go/menus"
+	"github.com/ratorx/chumenu-go/timeofday"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAdmin = "admin"
+	testUser  = "user"
+)
+
+// outbox records the messages sent to each recipient through cfg.sendClient
+type outbox struct {
+	mu   sync.Mutex
+	sent map[string][]string
+}
+
+// testOutbox points cfg.sendClient at a server which records the messages sent
+func testOutbox(t *testing.T) (*outbox, func()) {
+	o := &outbox{sent: make(map[string][]string)}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p facebook.Payload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Error(err)
+			return
+		}
+
+		o.mu.Lock()
+		o.sent[p.Recipient.ID] = append(o.sent[p.Recipient.ID], p.Message.Text)
+		o.mu.Unlock()
+		w.Write([]byte("{}"))
+	}))
+	cfg.sendClient = &facebook.SendClient{BaseURL: server.URL + "/"}
+
+	return o, server.Close
+}
+
+// to waits for n messages to be sent to the recipient, many of which are sent in the background, and returns
+// the messages sent so far
+func (o *outbox) to(recipient string, n int) []string {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		o.mu.Lock()
+		done := len(o.sent[recipient]) >= n
+		o.mu.Unlock()
+		if done {
+			break
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string{}, o.sent[recipient]...)
+}
+
+// last returns the last message sent to the recipient after waiting for n messages
+func (o *outbox) last(recipient string, n int) string {
+	sent := o.to(recipient, n)
+	if len(sent) == 0 {
+		return ""
+	}
+
+	return sent[len(sent)-1]
+}
+
+// slowSource is a Source which takes a while to return its week, like a slow download
+type slowSource menus.Week
+
+func (s slowSource) Week() (menus.Week, error) {
+	time.Sleep(20 * time.Millisecond)
+	return menus.Week(s), nil
+}
+
+// testConfig sets up cfg with a temporary database and a single venue serving lunch and dinner every day except
+// Sunday dinner, with menus from Monday 3rd to Monday 10th June 2019
+func testConfig(t *testing.T) (*venue, func()) {
+	testLocation(t)
+	cfg.debug = log.New(ioutil.Discard, "", 0)
+	cfg.calendar = calendar{}
+	cfg.userBucket = defaultUserBucket
+	cfg.admin = testAdmin
+
+	dir, err := ioutil.TempDir("", "chumenu")
+	require.NoError(t, err)
+
+	cfg.db, err = bolt.Open(filepath.Join(dir, "test.db"), 0600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	require.NoError(t, cfg.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range []string{cfg.userBucket, watchBucket, preferenceBucket, calendarBucket, announcementBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil { // nolint: vetshadow
+				return err
+			}
+		}
+		return nil
+	}))
+
+	week := make(menus.Static, 8)
+	for i := 0; i < 8; i++ {
+		date := time.Date(2019, time.June, 3+i, 0, 0, 0, 0, cfg.location)
+		day := date.Weekday().String()
+		week[menus.DateKey(date)] = menus.Menu{Date: date, Meals: []menus.NamedMeal{
+			{Name: menus.Lunch, Items: menus.Meal{{Name: day + " Lunch"}}},
+			{Name: menus.Dinner, Items: menus.Meal{{Name: day + " Dinner"}}},
+		}}
+	}
+
+	dinner := everyDay(dinnerTime)
+	dinner.Weekdays = map[time.Weekday]*mealTime{time.Sunday: nil}
+	v := &venue{name: "test", display: "Test", times: map[string]schedule{menus.Lunch: everyDay(lunchTime), menus.Dinner: dinner}}
+	// Fetched at the start of Monday
+	setClock(3, timeofday.New(0, 0))
+	v.menus, err = menus.NewCache(week, cfg.db, defaultMenuBucket, maxMenuAge, now)
+	require.NoError(t, err)
+	_, err = v.menus.Refresh()
+	require.NoError(t, err)
+	cfg.venues = []*venue{v}
+
+	return v, func() {
+		cfg.db.Close()
+		os.RemoveAll(dir)
+		cfg.clock = time.Now
+	}
+}
+
+// setClock fixes the time returned by now at the given wall clock time in London on day of June 2019
+func setClock(day int, tod timeofday.Time) {
+	t := tod.On(time.Date(2019, time.June, day, 0, 0, 0, 0, cfg.location), cfg.location)
+	cfg.clock = func() time.Time { return t }
+}
+
+func TestGetMenu_Boundaries(t *testing.T) {
+	v, cleanup := testConfig(t)
+	defer cleanup()
+
+	for _, c := range []struct {
+		meal   string
+		day    int // of June 2019, the 3rd is a Monday
+		time   timeofday.Time
+		prefix string
+		item   string
+	}{
+		// Lunch is shown until it ends at 13:45, then the next day's
+		{menus.Lunch, 3, timeofday.New(0, 0), "Today's Lunch:", "Monday Lunch"},
+		{menus.Lunch, 3, timeofday.New(11, 30), "Today's Lunch:", "Monday Lunch"},
+		{menus.Lunch, 3, timeofday.New(12, 15), "Today's Lunch:", "Monday Lunch"},
+		{menus.Lunch, 3, timeofday.New(13, 45), "Today's Lunch:", "Monday Lunch"},
+		{menus.Lunch, 3, timeofday.New(13, 46), "Tomorrow's Lunch:", "Tuesday Lunch"},
+		{menus.Lunch, 3, timeofday.New(23, 59), "Tomorrow's Lunch:", "Tuesday Lunch"},
+		// Dinner is shown until it ends at 19:15, then the next day's
+		{menus.Dinner, 3, timeofday.New(13, 46), "Today's Dinner:", "Monday Dinner"},
+		{menus.Dinner, 3, timeofday.New(19, 15), "Today's Dinner:", "Monday Dinner"},
+		{menus.Dinner, 3, timeofday.New(19, 16), "Tomorrow's Dinner:", "Tuesday Dinner"},
+		{menus.Dinner, 4, timeofday.New(0, 0), "Today's Dinner:", "Tuesday Dinner"},
+		// Dinner is not served on Sunday, so Saturday evening and Sunday show Monday's
+		{menus.Dinner, 8, timeofday.New(19, 16), "Monday's Dinner:", "Monday Dinner"},
+		{menus.Dinner, 9, timeofday.New(12, 0), "Tomorrow's Dinner:", "Monday Dinner"},
+		{menus.Lunch, 9, timeofday.New(12, 0), "Today's Lunch:", "Sunday Lunch"},
+	} {
+		setClock(c.day, c.time)
+		prefix, meal, err := getMenu(v, c.meal)
+		require.NoError(t, err, "%v on %v June at %v", c.meal, c.day, c.time)
+		assert.Equal(t, c.prefix, prefix, "%v on %v June at %v", c.meal, c.day, c.time)
+		assert.Equal(t, menus.Meal{{Name: c.item}}, meal, "%v on %v June at %v", c.meal, c.day, c.time)
+	}
+}
+
+func TestGetMenu_Closed(t *testing.T) {
+	v, cleanup := testConfig(t)
+	defer cleanup()
+
+	cfg.calendar.Closures = []closure{{Start: "2019-06-04", End: "2019-06-04"}}
+
+	// Closed tomorrow, so the closure is reported once today's lunch has ended
+	setClock(3, timeofday.New(13, 45))
+	_, _, err := getMenu(v, menus.Lunch)
+	assert.NoError(t, err)
+
+	setClock(3, timeofday.New(13, 46))
+	prefix, _, err := getMenu(v, menus.Lunch)
+	assert.Equal(t, "Tomorrow's Lunch:", prefix)
+	assert.IsType(t, &closedError{}, err)
+}
+
+func TestVenueMenus_FollowClock(t *testing.T) {
+	v, cleanup := testConfig(t)
+	defer cleanup()
+
+	// Fetched at the start of Monday, and stale a day later
+	setClock(3, timeofday.New(23, 59))
+	assert.False(t, v.menus.Stale())
+	setClock(4, timeofday.New(0, 1))
+	assert.True(t, v.menus.Stale())
+}
+
+func TestRefreshMenus_Overlapping(t *testing.T) {
+	v, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+
+	require.NoError(t, cfg.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket([]byte(watchBucket)).Put([]byte(testUser), []byte(`["curry"]`)); err != nil {
+			return err
+		}
+		return putSubscriber(tx.Bucket([]byte(cfg.userBucket)), []byte(testUser), subscriber{})
+	}))
+
+	// Monday's lunch changes to a watched dish during lunch
+	week, err := v.menus.Week()
+	require.NoError(t, err)
+	changed := make(menus.Week, len(week))
+	for k, m := range week {
+		changed[k] = m
+	}
+	monday := changed["2019-06-03"]
+	monday.Set(menus.Lunch, menus.Meal{{Name: "Curry"}})
+	changed["2019-06-03"] = monday
+
+	setClock(3, timeofday.New(12, 30))
+	v.menus, err = menus.NewCache(slowSource(changed), cfg.db, defaultMenuBucket, maxMenuAge, now)
+	require.NoError(t, err)
+	v.archive, err = menus.NewArchive(cfg.db, archiveBucket)
+	require.NoError(t, err)
+	v.health = &menus.Monitor{Threshold: healthThreshold, MaxUnchanged: healthMaxUnchanged, Cooldown: healthCooldown}
+
+	// As at startup, when the scheduler catches up a refresh
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			refreshMenus(v)
+		}()
+	}
+	wg.Wait()
+
+	// One watch alert and one change message
+	out.to(testUser, 2)
+	time.Sleep(50 * time.Millisecond)
+	sent := out.to(testUser, 0)
+	assert.Len(t, sent, 2, "Refreshes not serialised: %q", sent)
+	assert.Contains(t, sent, "Lunch changed: + Curry, - Monday Lunch")
+}
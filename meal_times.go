@@ -1,47 +1,35 @@
 package main
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
 
-type hourMinute struct {
-	Hour   uint8
-	Minute uint8
-}
-
-func (hm hourMinute) Before(minutes uint8) hourMinute {
-	// Setup for overflow
-	newHM := hourMinute{hm.Hour + 24, hm.Minute + 60}
-
-	hours := minutes / 60
-	minutes = minutes % 60
-
-	// Apply difference
-	if minutes > hm.Minute {
-		newHM.Hour -= hours + 1
-	} else {
-		newHM.Hour -= hours
-	}
-	newHM.Minute -= minutes
+	"github.com/ratorx/chumenu-go/timeofday"
+)
 
-	// Translate back to original interval
-	newHM.Hour %= 24
-	newHM.Minute %= 60
-
-	return newHM
+type mealTime struct {
+	Start timeofday.Time
+	End   timeofday.Time
 }
 
-func (hm hourMinute) IsAfter(other hourMinute) bool {
-	return hm.Hour > other.Hour || (hm.Hour == other.Hour && hm.Minute > other.Minute)
+func (mt mealTime) String() string {
+	return fmt.Sprintf("%s - %s", mt.Start, mt.End)
 }
 
-func (hm hourMinute) String() string {
-	return fmt.Sprintf("%02d:%02d", hm.Hour, hm.Minute)
-}
+// UnmarshalJSON reads a meal time of the form {"start": "12:15", "end": "13:45"}, where both times are required
+func (mt *mealTime) UnmarshalJSON(b []byte) error {
+	var aux struct {
+		Start *timeofday.Time
+		End   *timeofday.Time
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
 
-type mealTime struct {
-	Start hourMinute
-	End   hourMinute
-}
+	if aux.Start == nil || aux.End == nil {
+		return fmt.Errorf("schedule: meal time needs a start and an end")
+	}
 
-func (mt mealTime) String() string {
-	return fmt.Sprintf("%s - %s", mt.Start, mt.End)
+	*mt = mealTime{*aux.Start, *aux.End}
+	return nil
 }
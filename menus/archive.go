@@ -0,0 +1,115 @@
+package menus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrNotArchived is returned when the archive contains no menu for a date
+var ErrNotArchived = errors.New("Archive: no menu for date")
+
+// Archive is a history of menus stored in BoltDB, keyed by DateKey.
+// Since keys are ISO 8601 dates, the bucket is iterated in date order.
+type Archive struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// NewArchive creates an Archive which stores menus in bucket
+func NewArchive(db *bolt.DB, bucket string) (*Archive, error) {
+	a := &Archive{db: db, bucket: []byte(bucket)}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(a.bucket)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *Archive) getBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	b := tx.Bucket(a.bucket)
+	if b == nil {
+		return nil, fmt.Errorf("database corrupted: bucket %s not found", a.bucket)
+	}
+
+	return b, nil
+}
+
+// Put stores every day in week, replacing any previously archived menus for the same dates
+func (a *Archive) Put(week Week) error {
+	return a.db.Update(func(tx *bolt.Tx) error {
+		b, err := a.getBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		for key, m := range week {
+			v, err := json.Marshal(m)
+			if err != nil {
+				return err
+			}
+
+			if err = b.Put([]byte(key), v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Day returns the archived menu for the calendar date of t
+func (a *Archive) Day(t time.Time) (Menu, error) {
+	var m Menu
+
+	err := a.db.View(func(tx *bolt.Tx) error {
+		b, err := a.getBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		v := b.Get([]byte(DateKey(t)))
+		if v == nil {
+			return ErrNotArchived
+		}
+
+		return json.Unmarshal(v, &m)
+	})
+
+	return m, err
+}
+
+// Range returns the archived menus for the dates from start to end inclusive
+func (a *Archive) Range(start, end time.Time) (Week, error) {
+	week := Week{}
+	last := []byte(DateKey(end))
+
+	err := a.db.View(func(tx *bolt.Tx) error {
+		b, err := a.getBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		c := b.Cursor()
+		for k, v := c.Seek([]byte(DateKey(start))); k != nil && string(k) <= string(last); k, v = c.Next() {
+			var m Menu
+			if err := json.Unmarshal(v, &m); err != nil { // nolint: vetshadow
+				return err
+			}
+			week[string(k)] = m
+		}
+
+		return nil
+	})
+
+	return week, err
+}
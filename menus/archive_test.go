@@ -0,0 +1,42 @@
+package menus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchive(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	a, err := NewArchive(db, "archive")
+	require.NoError(t, err)
+	require.NoError(t, a.Put(Week(testWeek())))
+
+	m, err := a.Day(testMonday.Add(12 * time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, testMonday.Equal(m.Date), "Incorrect date archived")
+	assert.Equal(t, meal("Monday Lunch"), served(m, Lunch))
+
+	_, err = a.Day(testMonday.AddDate(0, 0, -1))
+	assert.Equal(t, ErrNotArchived, err, "Missing date found in archive")
+}
+
+func TestArchive_Range(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	a, err := NewArchive(db, "archive")
+	require.NoError(t, err)
+	require.NoError(t, a.Put(Week(testWeek())))
+
+	week, err := a.Range(testMonday.AddDate(0, 0, 1), testMonday.AddDate(0, 0, 3))
+	assert.NoError(t, err)
+	assert.Len(t, week, 3)
+	for i, m := range week.Days() {
+		assert.Equal(t, DateKey(testMonday.AddDate(0, 0, i+1)), DateKey(m.Date))
+	}
+}
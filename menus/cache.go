@@ -0,0 +1,193 @@
+package menus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+var (
+	weekKey    = []byte("week")
+	updatedKey = []byte("updated")
+)
+
+// ErrNotCached is returned by Cache.Week when no menus have been fetched yet
+var ErrNotCached = errors.New("Cache: no menus available")
+
+// Cache is a Source which serves the last successfully fetched week from memory.
+// The week is only fetched from the underlying Source when Refresh is called, and is persisted to BoltDB so that it survives restarts.
+type Cache struct {
+	source Source
+	db     *bolt.DB
+	bucket []byte
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu      sync.RWMutex
+	week    Week
+	updated time.Time
+	err     error
+}
+
+// NewCache creates a Cache for src, restoring the last good week stored in bucket.
+// The cached week is considered stale once it is older than maxAge, measured by the time returned by now.
+func NewCache(src Source, db *bolt.DB, bucket string, maxAge time.Duration, now func() time.Time) (*Cache, error) {
+	c := &Cache{source: src, db: db, bucket: []byte(bucket), maxAge: maxAge, now: now}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(c.bucket)
+		if err != nil {
+			return err
+		}
+
+		v := b.Get(weekKey)
+		if v == nil {
+			return nil
+		}
+
+		// Weeks stored in an older format are discarded and fetched again on the next refresh
+		if err := json.Unmarshal(v, &c.week); err != nil { // nolint: vetshadow
+			c.week = nil
+			return nil
+		}
+
+		return c.updated.UnmarshalText(b.Get(updatedKey))
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Week returns the cached menus without contacting the underlying Source
+func (c *Cache) Week() (Week, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.week == nil {
+		if c.err != nil {
+			return nil, c.err
+		}
+		return nil, ErrNotCached
+	}
+
+	return c.week, nil
+}
+
+// Refresh fetches the week from the underlying Source, and returns the changes from the previously cached week.
+// On failure the previously cached week is kept and the error is returned.
+// A partially parsed week replaces the cached week, and its Warnings are returned. Meals which the Warnings say
+// were left empty keep their previously cached items, so a missing column is not reported as the meal changing.
+func (c *Cache) Refresh() ([]Change, error) {
+	week, warnings := GetMenus(c.source)
+	if week == nil {
+		c.mu.Lock()
+		c.err = warnings
+		c.mu.Unlock()
+		return nil, warnings
+	}
+
+	if w, partial := warnings.(Warnings); partial {
+		c.mu.RLock()
+		week = keepFlagged(c.week, week, w)
+		c.mu.RUnlock()
+	}
+
+	updated := c.now()
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(c.bucket)
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %s not found", c.bucket)
+		}
+
+		v, err := json.Marshal(week) // nolint: vetshadow
+		if err != nil {
+			return err
+		}
+		if err = b.Put(weekKey, v); err != nil {
+			return err
+		}
+
+		v, err = updated.MarshalText()
+		if err != nil {
+			return err
+		}
+		return b.Put(updatedKey, v)
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changes := Diff(c.week, week)
+	// The fresh week is still served if it could not be persisted
+	c.week = week
+	c.updated = updated
+	if err == nil {
+		err = warnings
+	}
+	c.err = err
+
+	return changes, err
+}
+
+// Returns a copy of week in which the meals left empty by a LayoutError have their items from old, if any
+func keepFlagged(old, week Week, warnings Warnings) Week {
+	kept := make(Week, len(week))
+	for k, m := range week {
+		kept[k] = m
+	}
+
+	for _, w := range warnings {
+		e, ok := w.(*LayoutError)
+		if !ok || len(e.Meals) == 0 {
+			continue
+		}
+
+		o, ok := old.Day(e.Date)
+		if !ok {
+			continue
+		}
+		n, ok := kept.Day(e.Date)
+		if !ok {
+			continue
+		}
+
+		for _, name := range e.Meals {
+			if items, served := o.Meal(name); served {
+				n.Set(name, items)
+			}
+		}
+		kept[DateKey(e.Date)] = n
+	}
+
+	return kept
+}
+
+// Updated returns the time at which the cached week was last fetched successfully
+func (c *Cache) Updated() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.updated
+}
+
+// Err returns the error from the last call to Refresh, or nil if it succeeded without warnings
+func (c *Cache) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.err
+}
+
+// Stale reports whether the cached week is missing or older than the maximum age
+func (c *Cache) Stale() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.week == nil || c.now().Sub(c.updated) > c.maxAge
+}
@@ -0,0 +1,158 @@
+package menus
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+type failingSource struct{}
+
+func (failingSource) Week() (Week, error) {
+	return nil, errors.New("site down")
+}
+
+// page is a Source which parses the menus from an HTML page
+type page string
+
+func (p page) Week() (Week, error) {
+	root, err := html.Parse(strings.NewReader(string(p)))
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePage(root, testMonday, DefaultRules)
+}
+
+func testDB(t *testing.T) (*bolt.DB, func()) {
+	dir, err := ioutil.TempDir("", "menus")
+	require.NoError(t, err)
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCache_Refresh(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	c, err := NewCache(testWeek(), db, "menus", time.Hour, time.Now)
+	require.NoError(t, err)
+
+	_, err = c.Week()
+	assert.Equal(t, ErrNotCached, err, "Empty cache returned menus")
+	assert.True(t, c.Stale(), "Empty cache is not stale")
+
+	changes, err := c.Refresh()
+	require.NoError(t, err)
+	assert.Empty(t, changes, "Changes reported for the first week")
+
+	week, err := c.Week()
+	assert.NoError(t, err)
+	assert.Equal(t, Week(testWeek()), week, "Cached week does not match source")
+	assert.False(t, c.Stale(), "Fresh cache is stale")
+}
+
+func TestCache_KeepsLastGoodWeek(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	c, err := NewCache(testWeek(), db, "menus", time.Hour, time.Now)
+	require.NoError(t, err)
+	_, err = c.Refresh()
+	require.NoError(t, err)
+
+	// Restored from the database with a broken source
+	c, err = NewCache(failingSource{}, db, "menus", time.Hour, time.Now)
+	require.NoError(t, err)
+	_, err = c.Refresh()
+	assert.Error(t, err, "Source error not reported")
+	assert.Error(t, c.Err(), "Source error not recorded")
+
+	week, err := c.Week()
+	assert.NoError(t, err)
+	assert.Equal(t, Week(testWeek()), week, "Last good week not served")
+}
+
+func TestCache_RefreshChanges(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	c, err := NewCache(testWeek(), db, "menus", time.Hour, time.Now)
+	require.NoError(t, err)
+	_, err = c.Refresh()
+	require.NoError(t, err)
+
+	updated := testWeek()
+	m := updated[DateKey(testMonday)]
+	m.Set(Lunch, meal("Curry"))
+	updated[DateKey(testMonday)] = m
+
+	c.source = updated
+	changes, err := c.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Date: testMonday, Meal: "Lunch", Added: meal("Curry"), Removed: meal("Monday Lunch")}}, changes)
+}
+
+func TestCache_StaleFollowsClock(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	current := testMonday
+	c, err := NewCache(testWeek(), db, "menus", time.Hour, func() time.Time { return current })
+	require.NoError(t, err)
+	_, err = c.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, testMonday, c.Updated(), "Refresh not timed by the clock")
+
+	current = testMonday.Add(time.Hour)
+	assert.False(t, c.Stale(), "Stale at the maximum age")
+	current = testMonday.Add(time.Hour + time.Minute)
+	assert.True(t, c.Stale(), "Not stale after the maximum age")
+}
+
+func TestCache_RefreshKeepsMissingColumn(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	c, err := NewCache(testWeek(), db, "menus", time.Hour, time.Now)
+	require.NoError(t, err)
+	_, err = c.Refresh()
+	require.NoError(t, err)
+
+	// The dinner column has disappeared, and Monday's lunch has changed
+	rows := []string{"<table><tr><th></th><th>Lunch</th></tr>"}
+	for _, m := range Week(testWeek()).Days() {
+		lunch := served(m, Lunch)[0].Name
+		if m.Date.Equal(testMonday) {
+			lunch = "Curry"
+		}
+		rows = append(rows, "<tr><td>"+m.Date.Format("Monday 2 January")+"</td><td>"+lunch+"</td></tr>")
+	}
+	c.source = page(strings.Join(rows, "") + "</table>")
+
+	changes, err := c.Refresh()
+	assert.IsType(t, Warnings{}, err, "Missing column not reported")
+	assert.Equal(t, []Change{{Date: testMonday, Meal: "Lunch", Added: meal("Curry"), Removed: meal("Monday Lunch")}}, changes,
+		"Missing dinners reported as removed")
+
+	week, err := c.Week()
+	require.NoError(t, err)
+	for _, m := range week.Days() {
+		assert.Equal(t, meal(m.Date.Weekday().String()+" Dinner"), served(m, Dinner), "Dinner on %v not kept", m.Date)
+	}
+}
@@ -0,0 +1,132 @@
+package menus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseDate parses a loosely formatted date, e.g. "Monday 3rd December", "Mon 03/12/18" or "2018-12-03".
+// Parts of the date missing from text are taken from ref: the year is chosen to be closest to ref,
+// and a date given as just a weekday is assumed to be in the same week (Monday to Sunday) as ref.
+func ParseDate(text string, ref time.Time) (time.Time, error) {
+	var (
+		weekday    = -1
+		month      time.Month
+		numbers    []int
+		day, year  int
+		hasWeekday bool
+	)
+
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, f := range fields {
+		if unicode.IsDigit(rune(f[0])) {
+			n, err := strconv.Atoi(strings.TrimRight(f, "stndrh"))
+			if err == nil {
+				numbers = append(numbers, n)
+			}
+			continue
+		}
+
+		if w, ok := matchName(f, weekdayNames[:]); ok && !hasWeekday {
+			weekday, hasWeekday = w, true
+		} else if m, ok := matchName(f, monthNames[:]); ok && month == 0 {
+			month = time.Month(m + 1)
+		}
+	}
+
+	switch {
+	case month != 0 && len(numbers) > 0:
+		// Day and month name, with an optional year
+		day = numbers[0]
+		if len(numbers) > 1 {
+			year = numbers[1]
+		}
+	case month == 0 && len(numbers) > 2 && numbers[0] > 31:
+		// ISO 8601 year-month-day
+		year, month, day = numbers[0], time.Month(numbers[1]), numbers[2]
+	case month == 0 && len(numbers) > 1:
+		// Numeric day/month with an optional year
+		day, month = numbers[0], time.Month(numbers[1])
+		if len(numbers) > 2 {
+			year = numbers[2]
+		}
+	case hasWeekday:
+		// Only the weekday is known
+		// Weeks run from Monday to Sunday, so convert both weekdays to offsets from Monday
+		offset := (weekday+6)%7 - (int(ref.Weekday())+6)%7
+		y, m, d := ref.Date()
+		return time.Date(y, m, d+offset, 0, 0, 0, 0, ref.Location()), nil
+	default:
+		return time.Time{}, fmt.Errorf("Date: Unable to parse date from %q", text)
+	}
+
+	if month < time.January || month > time.December || day < 1 || day > 31 {
+		return time.Time{}, fmt.Errorf("Date: Invalid date %q", text)
+	}
+
+	if year == 0 {
+		if t, ok := closestYear(day, month, ref); ok {
+			return t, nil
+		}
+	} else {
+		if year < 100 {
+			year += 2000
+		}
+		if t, ok := validDate(year, month, day, ref.Location()); ok {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Date: Invalid date %q", text)
+}
+
+// Returns the occurrence of day and month closest to ref, if the date exists in a year near ref
+func closestYear(day int, month time.Month, ref time.Time) (time.Time, bool) {
+	var best time.Time
+	found := false
+	for _, y := range []int{ref.Year(), ref.Year() - 1, ref.Year() + 1} {
+		t, ok := validDate(y, month, day, ref.Location())
+		if ok && (!found || abs(t.Sub(ref)) < abs(best.Sub(ref))) {
+			best, found = t, true
+		}
+	}
+
+	return best, found
+}
+
+// Returns the date, unless time.Date would normalise it into the next month, e.g. 31st February
+func validDate(year int, month time.Month, day int, loc *time.Location) (time.Time, bool) {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	return t, t.Month() == month && t.Day() == day
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+var weekdayNames = [...]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+var monthNames = [...]string{"january", "february", "march", "april", "may", "june", "july", "august", "september", "october", "november", "december"}
+
+// Matches a full name or an abbreviation of at least 3 letters
+func matchName(s string, names []string) (int, bool) {
+	if len(s) < 3 {
+		return 0, false
+	}
+
+	for i, name := range names {
+		if strings.HasPrefix(name, s) {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,54 @@
+package menus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parseDateTest struct {
+	Case     string
+	Ref      time.Time
+	Expected time.Time
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func parseDateCases() []parseDateTest {
+	wednesday := date(2018, time.December, 5)
+	return []parseDateTest{
+		// Day and month names
+		{"Monday 3rd December", wednesday, date(2018, time.December, 3)},
+		{"Sunday\xa09th Dec", wednesday, date(2018, time.December, 9)},
+		{"Tues 1st January", date(2018, time.December, 29), date(2019, time.January, 1)},
+		{"Monday 31st December", date(2019, time.January, 2), date(2018, time.December, 31)},
+		{"Monday 3 December 2018", date(2020, time.June, 1), date(2018, time.December, 3)},
+		{"Saturday 29th February", date(2019, time.December, 5), date(2020, time.February, 29)},
+		// Numeric dates
+		{"Mon 03/12", wednesday, date(2018, time.December, 3)},
+		{"Mon 03/12/18", wednesday, date(2018, time.December, 3)},
+		{"2018-12-03", date(2020, time.June, 1), date(2018, time.December, 3)},
+		// Weekday only
+		{"Monday", wednesday, date(2018, time.December, 3)},
+		{"Sunday", wednesday, date(2018, time.December, 9)},
+		{"Wed", date(2018, time.December, 9), date(2018, time.December, 5)},
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	for _, pdt := range parseDateCases() {
+		actual, err := ParseDate(pdt.Case, pdt.Ref)
+		assert.NoError(t, err, pdt.Case)
+		assert.Equal(t, pdt.Expected, actual, "Incorrect date parsed from %q", pdt.Case)
+	}
+}
+
+func TestParseDate_Invalid(t *testing.T) {
+	for _, c := range []string{"", "Lunch", "32nd December", "14/13", "31/2", "31st April", "29/2/19"} {
+		_, err := ParseDate(c, date(2018, time.December, 5))
+		assert.Error(t, err, "Invalid date %q parsed", c)
+	}
+}
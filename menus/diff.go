@@ -0,0 +1,79 @@
+package menus
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Change contains the items added to and removed from a meal between two versions of a week
+type Change struct {
+	Date    time.Time
+	Meal    string
+	Added   Meal
+	Removed Meal
+}
+
+func (c Change) String() string {
+	parts := make([]string, 0, len(c.Added)+len(c.Removed))
+	for _, item := range c.Added {
+		parts = append(parts, "+ "+item.String())
+	}
+	for _, item := range c.Removed {
+		parts = append(parts, "- "+item.String())
+	}
+
+	return fmt.Sprintf("%s changed: %s", c.Meal, strings.Join(parts, ", "))
+}
+
+// Diff compares the meals of each day present in both old and new, and returns the changes in date order.
+// Days which only appear in one of the weeks are not compared.
+func Diff(old, new Week) []Change {
+	var changes []Change
+
+	for _, n := range new.Days() {
+		o, ok := old.Day(n.Date)
+		if !ok {
+			continue
+		}
+
+		// Meals only on one version of the day are compared against an empty meal
+		both := o
+		for _, nm := range n.Meals {
+			both.Set(nm.Name, nm.Items)
+		}
+
+		for _, nm := range both.Meals {
+			oldMeal, _ := o.Meal(nm.Name)
+			newMeal, _ := n.Meal(nm.Name)
+			if c, changed := diffMeal(n.Date, nm.Name, oldMeal, newMeal); changed {
+				changes = append(changes, c)
+			}
+		}
+	}
+
+	return changes
+}
+
+func diffMeal(date time.Time, name string, old, new Meal) (Change, bool) {
+	c := Change{Date: date, Meal: name, Added: missing(new, old), Removed: missing(old, new)}
+	return c, len(c.Added) != 0 || len(c.Removed) != 0
+}
+
+// Returns the items in a which are not in b
+// Items are compared including their tags
+func missing(a, b Meal) Meal {
+	present := make(map[string]bool, len(b))
+	for _, item := range b {
+		present[item.String()] = true
+	}
+
+	var ret Meal
+	for _, item := range a {
+		if !present[item.String()] {
+			ret = append(ret, item)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,36 @@
+package menus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	old := Week(testWeek())
+	new := Week(testWeek())
+
+	tuesday := testMonday.AddDate(0, 0, 1)
+	m := new[DateKey(tuesday)]
+	m.Set(Dinner, meal("Lasagne", "Chips"))
+	new[DateKey(tuesday)] = m
+
+	changes := Diff(old, new)
+	assert.Equal(t, []Change{{Date: tuesday, Meal: "Dinner", Added: meal("Lasagne", "Chips"), Removed: meal("Tuesday Dinner")}}, changes)
+	assert.Equal(t, "Dinner changed: + Lasagne, + Chips, - Tuesday Dinner", changes[0].String())
+}
+
+func TestDiff_Unchanged(t *testing.T) {
+	assert.Empty(t, Diff(Week(testWeek()), Week(testWeek())), "Identical weeks reported as changed")
+}
+
+func TestDiff_NewWeek(t *testing.T) {
+	next := Week{}
+	for _, m := range testWeek() {
+		m.Date = m.Date.AddDate(0, 0, 7)
+		m.Set(Lunch, meal("Something else"))
+		next[DateKey(m.Date)] = m
+	}
+
+	assert.Empty(t, Diff(Week(testWeek()), next), "Days missing from the old week compared")
+}
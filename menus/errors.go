@@ -0,0 +1,92 @@
+package menus
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Maximum length of the HTML included in errors
+const maxSnippet = 300
+
+// NetworkError is returned when the menu page could not be downloaded
+type NetworkError struct {
+	URL string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("Scraper: Unable to download %v (%v)", e.URL, e.Err)
+}
+
+// LayoutError is returned when part of the menu page is not laid out as expected.
+// Row is the row of the menu table containing the problem, or 0 if it affects the whole page.
+type LayoutError struct {
+	Row     int
+	Reason  string
+	Snippet string    // HTML of the offending part of the page
+	Date    time.Time // date of the row, if it could be parsed
+	Meals   []string  // meals on Date which were left empty because of the problem
+}
+
+func (e *LayoutError) Error() string {
+	if e.Row == 0 {
+		return "Scraper: " + e.Reason
+	}
+
+	return fmt.Sprintf("Scraper: row %v: %v", e.Row, e.Reason)
+}
+
+// DayMissingError is returned when there is no menu for a date
+type DayMissingError struct {
+	Date    time.Time
+	Snippet string // HTML of the menu table the date is missing from, if any
+}
+
+func (e *DayMissingError) Error() string {
+	return fmt.Sprintf("Scraper: No menu for %v", e.Date.Format("Monday 2 January"))
+}
+
+// EmptyTableError is returned when the menu table does not contain any menus, usually because they have not been published yet
+type EmptyTableError struct {
+	Snippet string // HTML of the menu table
+}
+
+func (e *EmptyTableError) Error() string {
+	return "Scraper: No menus found in table"
+}
+
+// Warnings is returned as the error alongside a partially parsed week.
+// Each warning is a *LayoutError or *DayMissingError describing a part of the page which was skipped.
+type Warnings []error
+
+func (w Warnings) Error() string {
+	parts := make([]string, len(w))
+	for i := range w {
+		parts[i] = strings.TrimPrefix(w[i].Error(), "Scraper: ")
+	}
+
+	return "Scraper: " + strings.Join(parts, "; ")
+}
+
+// Renders the HTML of n, truncated to maxSnippet bytes
+func snippet(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+
+	var b bytes.Buffer
+	if err := html.Render(&b, n); err != nil {
+		return ""
+	}
+
+	s := b.String()
+	if len(s) > maxSnippet {
+		s = s[:maxSnippet] + "..."
+	}
+
+	return s
+}
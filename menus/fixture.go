@@ -0,0 +1,54 @@
+package menus
+
+import (
+	"strings"
+	"time"
+)
+
+// Fixture is the recorded result of parsing a saved menu page.
+// Fixtures are stored as JSON next to the page, and used to check that changes to the parser do not change its output.
+// Warnings and errors are recorded as their kind followed by their message, e.g. "layout: row 3: no lunch column".
+type Fixture struct {
+	Ref      time.Time // time the page was saved
+	Week     Week      `json:",omitempty"`
+	Warnings []string  `json:",omitempty"`
+	Error    string    `json:",omitempty"`
+}
+
+// RecordFixture parses the page saved at path as if it was downloaded at ref.
+// Parse failures are recorded in the Fixture, so the only errors returned are from reading the file.
+func RecordFixture(path string, ref time.Time, rules Rules) (Fixture, error) {
+	f := Fixture{Ref: ref}
+
+	week, err := File{Path: path, Rules: rules, Ref: ref}.Week()
+	switch e := err.(type) {
+	case nil:
+	case Warnings:
+		for _, w := range e {
+			f.Warnings = append(f.Warnings, describe(w))
+		}
+	case *LayoutError, *EmptyTableError:
+		f.Error = describe(e)
+	default:
+		return f, err
+	}
+
+	f.Week = week
+	return f, nil
+}
+
+func describe(err error) string {
+	var kind string
+	switch err.(type) {
+	case *LayoutError:
+		kind = "layout"
+	case *DayMissingError:
+		kind = "day missing"
+	case *EmptyTableError:
+		kind = "empty table"
+	default:
+		kind = "other"
+	}
+
+	return kind + ": " + strings.TrimPrefix(err.Error(), "Scraper: ")
+}
@@ -0,0 +1,128 @@
+package menus
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Monitor tracks the results of refreshing the menus, and decides when the scraper looks broken.
+// A problem is reported after Threshold consecutive failures, when a week has no meals at all, or when the
+// meals have not changed for MaxUnchanged. Reports are limited to one every Cooldown.
+type Monitor struct {
+	Threshold    int
+	MaxUnchanged time.Duration
+	Cooldown     time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	content   string
+	sameSince time.Time
+	lastAlert time.Time
+	alerting  bool
+}
+
+// Check records the result of a refresh at now, and returns a diagnostic message if one should be sent.
+// Once the scraper recovers after a problem was reported, a single recovery message is returned.
+func (m *Monitor) Check(week Week, err error, now time.Time) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	problem := m.problem(week, err, now)
+	if problem == "" {
+		if m.alerting {
+			m.alerting = false
+			m.lastAlert = time.Time{}
+			return "Menu scraper recovered.", true
+		}
+		return "", false
+	}
+
+	if now.Sub(m.lastAlert) < m.Cooldown {
+		return "", false
+	}
+
+	m.alerting = true
+	m.lastAlert = now
+	return "Menu scraper problem: " + problem, true
+}
+
+// Returns a description of the problem with the refresh, or "" if it looks healthy
+func (m *Monitor) problem(week Week, err error, now time.Time) string {
+	if _, partial := err.(Warnings); err != nil && !partial {
+		m.failures++
+		if m.failures < m.Threshold {
+			return ""
+		}
+
+		return fmt.Sprintf("%v consecutive failures.\nLast error: %v%v", m.failures, err, errorSnippet(err))
+	}
+	m.failures = 0
+
+	if empty(week) {
+		return fmt.Sprintf("every meal is empty.%v", warningSummary(err))
+	}
+
+	content := weekContent(week)
+	if content != m.content {
+		m.content = content
+		m.sameSince = now
+		return ""
+	}
+
+	if unchanged := now.Sub(m.sameSince); unchanged > m.MaxUnchanged {
+		return fmt.Sprintf("meals unchanged for %v.%v", unchanged.Round(time.Hour), warningSummary(err))
+	}
+
+	return ""
+}
+
+// Reports whether every meal of the week is empty
+func empty(week Week) bool {
+	for _, m := range week {
+		for _, meal := range m.Meals {
+			if len(meal.Items) != 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Returns the meals in a week in order, ignoring the dates so that repeated weeks are detected
+func weekContent(week Week) string {
+	var b strings.Builder
+	for _, m := range week.Days() {
+		for _, meal := range m.Meals {
+			fmt.Fprintf(&b, "%v:%v|", meal.Name, meal.Items)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func errorSnippet(err error) string {
+	var s string
+	switch e := err.(type) {
+	case *LayoutError:
+		s = e.Snippet
+	case *EmptyTableError:
+		s = e.Snippet
+	}
+
+	if s == "" {
+		return ""
+	}
+	return "\nHTML: " + s
+}
+
+func warningSummary(err error) string {
+	if w, ok := err.(Warnings); ok {
+		return fmt.Sprintf("\n%v warnings: %v", len(w), w)
+	}
+
+	return ""
+}
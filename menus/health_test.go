@@ -0,0 +1,74 @@
+package menus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMonitor() *Monitor {
+	return &Monitor{Threshold: 3, MaxUnchanged: 8 * 24 * time.Hour, Cooldown: 6 * time.Hour}
+}
+
+func TestMonitor_Failures(t *testing.T) {
+	m := testMonitor()
+	now := testMonday
+	failure := &NetworkError{URL: ChurchillURL, Err: errors.New("timeout")}
+
+	for i := 0; i < 2; i++ {
+		_, alert := m.Check(nil, failure, now)
+		assert.False(t, alert, "Alerted before threshold")
+		now = now.Add(time.Hour)
+	}
+
+	msg, alert := m.Check(nil, failure, now)
+	assert.True(t, alert, "No alert at threshold")
+	assert.Contains(t, msg, "timeout")
+
+	// Cooldown
+	now = now.Add(time.Hour)
+	_, alert = m.Check(nil, failure, now)
+	assert.False(t, alert, "Alerted during cooldown")
+
+	now = now.Add(6 * time.Hour)
+	_, alert = m.Check(nil, failure, now)
+	assert.True(t, alert, "No alert after cooldown")
+
+	// Recovery
+	msg, alert = m.Check(Week(testWeek()), nil, now.Add(time.Hour))
+	assert.True(t, alert, "No recovery message")
+	assert.Contains(t, msg, "recovered")
+
+	_, alert = m.Check(Week(testWeek()), nil, now.Add(2*time.Hour))
+	assert.False(t, alert, "Recovery reported twice")
+}
+
+func TestMonitor_Empty(t *testing.T) {
+	m := testMonitor()
+	week := NewWeek(Menu{Date: testMonday}, Menu{Date: testMonday.AddDate(0, 0, 1)})
+
+	_, alert := m.Check(week, nil, testMonday)
+	assert.True(t, alert, "Empty week not reported")
+}
+
+func TestMonitor_Unchanged(t *testing.T) {
+	m := testMonitor()
+	now := testMonday
+
+	// The same meals republished with next week's dates
+	for day := 0; day <= 8; day++ {
+		week := Week{}
+		for _, menu := range testWeek() {
+			menu.Date = menu.Date.AddDate(0, 0, day/7*7)
+			week[DateKey(menu.Date)] = menu
+		}
+
+		_, alert := m.Check(week, nil, now.AddDate(0, 0, day))
+		assert.False(t, alert, "Alerted after %v days", day)
+	}
+
+	_, alert := m.Check(Week(testWeek()), nil, now.AddDate(0, 0, 9))
+	assert.True(t, alert, "Unchanged meals not reported")
+}
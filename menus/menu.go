@@ -3,52 +3,56 @@ package menus
 import (
 	"fmt"
 	"time"
-
-	"github.com/yhat/scrape"
-	"golang.org/x/net/html/atom"
 )
 
-// GetData returns a Datablock which contains the menus for the provided weekday and the following day
-func GetData(weekday time.Weekday) (Datablock, error) {
-	table, err := getTable()
-	if err != nil {
+// GetData returns a Datablock which contains the menus for the date of t and the following day.
+// Days missing from the source's week have empty meals rather than falling back to another week.
+// As with GetMenus, a partially parsed week is used and Warnings are returned as the error.
+func GetData(src Source, t time.Time) (Datablock, error) {
+	week, err := GetMenus(src)
+	if week == nil {
 		return Datablock{}, err
 	}
 
-	menus := scrape.FindAll(table, scrape.ByTag(atom.Tr))
-	if len(menus) != 8 {
-		return Datablock{}, fmt.Errorf("Scraper: Menus array is not the correct length (Expected: 8, Actual: %v)", len(menus))
-	}
+	return Datablock{Current: day(week, t), Next: day(week, t.AddDate(0, 0, 1))}, err
+}
 
-	// Convert from Weekday to slice index
-	// Only need to change Sunday, int value of everything else is correct
-	index := int(weekday)
-	if weekday == time.Sunday {
-		index = 7
+// GetDay returns the menu for the date of t.
+// A *DayMissingError is returned if the source's week does not include the date.
+func GetDay(src Source, t time.Time) (Menu, error) {
+	week, err := GetMenus(src)
+	if week == nil {
+		return Menu{}, err
 	}
 
-	ret := Datablock{}
-	menu, err := parseDay(menus[index])
-	if err != nil {
-		return ret, err
+	m, ok := week.Day(t)
+	if !ok {
+		return day(week, t), &DayMissingError{Date: t}
 	}
-	ret.Current = menu
 
-	menu, err = parseDay(menus[(index%7)+1])
-	if err != nil {
-		return ret, err
+	return m, err
+}
+
+func day(week Week, t time.Time) Menu {
+	if m, ok := week.Day(t); ok {
+		return m
 	}
-	ret.Next = menu
 
-	return ret, nil
+	y, m, d := t.Date()
+	return Menu{Date: time.Date(y, m, d, 0, 0, 0, 0, t.Location())}
 }
 
-// GetMenus returns a slice containing the menus for the entire week
-func GetMenus() ([]Menu, error) {
-	table, err := getTable()
-	if err != nil {
+// GetMenus returns the menus for the entire week.
+// If the source could only partially parse the week, it is returned along with the Warnings as the error.
+func GetMenus(src Source) (Week, error) {
+	week, err := src.Week()
+	if _, partial := err.(Warnings); err != nil && !partial {
 		return nil, err
 	}
 
-	return parseWeek(table)
+	if len(week) == 0 {
+		return nil, fmt.Errorf("Source: No menus available")
+	}
+
+	return week, err
 }
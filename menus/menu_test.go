@@ -0,0 +1,93 @@
+package menus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Monday 3rd December 2018
+var testMonday = time.Date(2018, time.December, 3, 0, 0, 0, 0, time.UTC)
+
+func testWeek() Static {
+	week := make(Static, 7)
+	for i := 0; i < 7; i++ {
+		date := testMonday.AddDate(0, 0, i)
+		day := date.Weekday().String()
+		week[DateKey(date)] = Menu{Date: date, Meals: []NamedMeal{{Lunch, meal(day + " Lunch")}, {Dinner, meal(day + " Dinner")}}}
+	}
+
+	return week
+}
+
+func TestGetData(t *testing.T) {
+	src := testWeek()
+
+	block, err := GetData(src, testMonday.Add(13*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, src[DateKey(testMonday)], block.Current, "Incorrect menu for Monday")
+	assert.Equal(t, src[DateKey(testMonday.AddDate(0, 0, 1))], block.Next, "Incorrect menu for Tuesday")
+}
+
+func TestGetData_WeekRollover(t *testing.T) {
+	src := testWeek()
+	sunday := testMonday.AddDate(0, 0, 6)
+
+	block, err := GetData(src, sunday.Add(20*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, src[DateKey(sunday)], block.Current, "Incorrect menu for Sunday")
+	assert.Equal(t, testMonday.AddDate(0, 0, 7), block.Next.Date, "Incorrect date for the following Monday")
+	assert.Empty(t, served(block.Next, Lunch), "Previous week's lunch served for the following Monday")
+	assert.Empty(t, served(block.Next, Dinner), "Previous week's dinner served for the following Monday")
+}
+
+func TestGetMenus_Empty(t *testing.T) {
+	_, err := GetMenus(Static{})
+	assert.Error(t, err, "Empty week not rejected")
+}
+
+func TestWeek_Days(t *testing.T) {
+	days := Week(testWeek()).Days()
+	assert.Len(t, days, 7)
+	for i, m := range days {
+		assert.Equal(t, testMonday.AddDate(0, 0, i), m.Date, "Days not in date order")
+	}
+}
+
+func TestGetDay(t *testing.T) {
+	src := testWeek()
+
+	m, err := GetDay(src, testMonday.Add(13*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, src[DateKey(testMonday)], m)
+
+	_, err = GetDay(src, testMonday.AddDate(0, 0, 7))
+	assert.IsType(t, &DayMissingError{}, err, "Missing day not reported")
+}
+
+func TestGetDay_Location(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	// The clocks go forward on Sunday 31st March 2019
+	monday := time.Date(2019, time.March, 25, 0, 0, 0, 0, london)
+	sunday, err := ParseDate("sunday", monday)
+	require.NoError(t, err)
+	next, err := ParseDate("1/4", sunday)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2019, time.March, 31, 0, 0, 0, 0, london), sunday)
+
+	src := Static(NewWeek(Menu{Date: sunday, Meals: []NamedMeal{{Lunch, meal("Roast")}}}, Menu{Date: next, Meals: []NamedMeal{{Lunch, meal("Soup")}}}))
+
+	// 23:30 UTC on Sunday is already Monday in London
+	late := time.Date(2019, time.March, 31, 23, 30, 0, 0, time.UTC)
+	m, err := GetDay(src, late.In(london))
+	assert.NoError(t, err)
+	assert.Equal(t, meal("Soup"), served(m, Lunch), "Day chosen in the wrong time zone")
+
+	m, err = GetDay(src, late)
+	assert.NoError(t, err)
+	assert.Equal(t, meal("Roast"), served(m, Lunch))
+}
@@ -1,14 +1,21 @@
 package menus
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 )
 
-const emptyMeal = " - To Be Confirmed"
+const (
+	emptyMeal  = " - To Be Confirmed"
+	emptyLine  = "To Be Confirmed"
+	dateLayout = "2006-01-02"
+)
 
 // Meal represents a list of food items
-type Meal []string
+type Meal []Item
 
 func (m Meal) String() string {
 	// fmt.Println()
@@ -16,17 +23,164 @@ func (m Meal) String() string {
 		return emptyMeal
 	}
 
-	return fmt.Sprintf(" - %s", strings.Join(m, "\n - "))
+	items := make([]string, len(m))
+	for i := range m {
+		items[i] = m[i].String()
+	}
+
+	return fmt.Sprintf(" - %s", strings.Join(items, "\n - "))
+}
+
+// Summary returns the items in the meal on a single line
+func (m Meal) Summary() string {
+	if len(m) == 0 {
+		return emptyLine
+	}
+
+	items := make([]string, len(m))
+	for i := range m {
+		items[i] = m[i].String()
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// Filter returns the items in the meal for which keep returns true
+func (m Meal) Filter(keep func(Item) bool) Meal {
+	ret := make(Meal, 0, len(m))
+	for _, item := range m {
+		if keep(item) {
+			ret = append(ret, item)
+		}
+	}
+
+	return ret
+}
+
+// Tagged returns the items in the meal tagged with t
+func (m Meal) Tagged(t Tag) Meal {
+	return m.Filter(func(i Item) bool { return i.Has(t) })
+}
+
+// Names of the meals which can be scraped
+const (
+	Breakfast = "Breakfast"
+	Brunch    = "Brunch"
+	Lunch     = "Lunch"
+	Dinner    = "Dinner"
+	Formal    = "Formal Hall"
+)
+
+// MealNames lists the meals in the order they are served during a day
+var MealNames = []string{Breakfast, Brunch, Lunch, Dinner, Formal}
+
+// MealName returns the name of the meal called name, ignoring case.
+// "supper" is treated as dinner and "formal" as formal hall.
+func MealName(name string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "breakfast":
+		return Breakfast, true
+	case "brunch":
+		return Brunch, true
+	case "lunch":
+		return Lunch, true
+	case "dinner", "supper":
+		return Dinner, true
+	case "formal", "formal hall":
+		return Formal, true
+	}
+
+	return "", false
 }
 
-// Menu is a struct which contains the meals provided for lunch and dinner
+// Returns the position of a meal in MealNames, with unknown meals last
+func mealIndex(name string) int {
+	for i, n := range MealNames {
+		if n == name {
+			return i
+		}
+	}
+
+	return len(MealNames)
+}
+
+// NamedMeal is a meal served under a name, e.g. "Lunch"
+type NamedMeal struct {
+	Name  string
+	Items Meal
+}
+
+// Menu is a struct which contains the meals provided on a particular date, in the order they are served
 type Menu struct {
-	Lunch  Meal
-	Dinner Meal
+	Date  time.Time
+	Meals []NamedMeal
+}
+
+// Meal returns the items of the meal called name, and whether the menu includes it
+func (m Menu) Meal(name string) (Meal, bool) {
+	for _, nm := range m.Meals {
+		if nm.Name == name {
+			return nm.Items, true
+		}
+	}
+
+	return nil, false
+}
+
+// Set replaces the items of the meal called name, adding it in serving order if it is not on the menu.
+// Copies of a Menu share their meals, so the meals are copied rather than modified in place.
+func (m *Menu) Set(name string, items Meal) {
+	meals := make([]NamedMeal, 0, len(m.Meals)+1)
+	added := false
+	for _, nm := range m.Meals {
+		if !added && (nm.Name == name || mealIndex(nm.Name) > mealIndex(name)) {
+			meals = append(meals, NamedMeal{Name: name, Items: items})
+			added = true
+		}
+		if nm.Name != name {
+			meals = append(meals, nm)
+		}
+	}
+	if !added {
+		meals = append(meals, NamedMeal{Name: name, Items: items})
+	}
+
+	m.Meals = meals
 }
 
 func (m Menu) String() string {
-	return fmt.Sprintf("\nLunch:\n%s\nDinner:\n%s\n", m.Lunch, m.Dinner)
+	var b strings.Builder
+	for _, nm := range m.Meals {
+		fmt.Fprintf(&b, "\n%s:\n%s", nm.Name, nm.Items)
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// UnmarshalJSON reads a menu, including menus stored before meals were named which have Lunch and Dinner fields
+func (m *Menu) UnmarshalJSON(b []byte) error {
+	type menu Menu // without the UnmarshalJSON method
+	var aux struct {
+		menu
+		Lunch  *Meal
+		Dinner *Meal
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	*m = Menu(aux.menu)
+	if len(m.Meals) == 0 {
+		if aux.Lunch != nil {
+			m.Set(Lunch, *aux.Lunch)
+		}
+		if aux.Dinner != nil {
+			m.Set(Dinner, *aux.Dinner)
+		}
+	}
+
+	return nil
 }
 
 // Datablock is a struct which contains menus of 2 consecutive days
@@ -34,3 +188,39 @@ type Datablock struct {
 	Current Menu
 	Next    Menu
 }
+
+// DateKey returns the key identifying the calendar date of t in a Week.
+// The date is taken in t's location, so t should be in the time zone of the menus.
+func DateKey(t time.Time) string {
+	return t.Format(dateLayout)
+}
+
+// Week contains the menus for a week, keyed by DateKey
+type Week map[string]Menu
+
+// NewWeek builds a Week from a list of dated menus
+func NewWeek(menus ...Menu) Week {
+	w := make(Week, len(menus))
+	for _, m := range menus {
+		w[DateKey(m.Date)] = m
+	}
+
+	return w
+}
+
+// Day returns the menu for the calendar date of t
+func (w Week) Day(t time.Time) (Menu, bool) {
+	m, ok := w[DateKey(t)]
+	return m, ok
+}
+
+// Days returns the menus in the week in date order
+func (w Week) Days() []Menu {
+	days := make([]Menu, 0, len(w))
+	for _, m := range w {
+		days = append(days, m)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+
+	return days
+}
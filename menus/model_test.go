@@ -1,12 +1,30 @@
 package menus
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// Builds a Meal of untagged items
+func meal(names ...string) Meal {
+	m := make(Meal, len(names))
+	for i, n := range names {
+		m[i] = Item{Name: n}
+	}
+
+	return m
+}
+
+// Returns the items of a meal on a menu, or nil if it is not on the menu
+func served(m Menu, name string) Meal {
+	items, _ := m.Meal(name)
+	return items
+}
+
 type mealTest struct {
 	Case     Meal
 	Expected string
@@ -15,8 +33,9 @@ type mealTest struct {
 func mealCases() []mealTest {
 	return []mealTest{
 		mealTest{Meal{}, emptyMeal},
-		mealTest{Meal{"one"}, " - one"},
-		mealTest{Meal{"one", "two"}, " - one\n - two"},
+		mealTest{meal("one"), " - one"},
+		mealTest{meal("one", "two"), " - one\n - two"},
+		mealTest{Meal{{"one", []Tag{Vegan, GlutenFree}}, {"two", nil}}, " - one (VG, GF)\n - two"},
 	}
 }
 
@@ -26,6 +45,11 @@ func TestMeal_String(t *testing.T) {
 	}
 }
 
+func TestMeal_Summary(t *testing.T) {
+	assert.Equal(t, emptyLine, Meal{}.Summary())
+	assert.Equal(t, "one (VG, GF), two", Meal{{"one", []Tag{Vegan, GlutenFree}}, {"two", nil}}.Summary())
+}
+
 type menuTest struct {
 	Case     Menu
 	Expected string
@@ -33,7 +57,8 @@ type menuTest struct {
 
 func menuCases() []menuTest {
 	return []menuTest{
-		menuTest{Menu{Meal{}, Meal{}}, fmt.Sprintf("\nLunch:\n%s\nDinner:\n%s\n", emptyMeal, emptyMeal)},
+		menuTest{Menu{Meals: []NamedMeal{{Lunch, Meal{}}, {Dinner, Meal{}}}}, fmt.Sprintf("\nLunch:\n%s\nDinner:\n%s\n", emptyMeal, emptyMeal)},
+		menuTest{Menu{Meals: []NamedMeal{{Brunch, meal("Eggs")}}}, "\nBrunch:\n - Eggs\n"},
 	}
 }
 
@@ -42,3 +67,43 @@ func TestMenu_String(t *testing.T) {
 		assert.Equal(t, mt.Expected, mt.Case.String(), "Menu String conversion failed")
 	}
 }
+
+func TestMeal_Tagged(t *testing.T) {
+	m := Meal{{"Curry", []Tag{Vegan}}, {"Steak", nil}, {"Quiche", []Tag{Vegetarian}}}
+	assert.Equal(t, Meal{{"Curry", []Tag{Vegan}}}, m.Tagged(Vegan))
+	assert.Empty(t, m.Tagged(ContainsNuts))
+}
+
+func TestMenu_Set(t *testing.T) {
+	var m Menu
+	m.Set(Dinner, meal("Steak"))
+	m.Set(Breakfast, meal("Toast"))
+	m.Set(Lunch, meal("Soup"))
+
+	copied := m
+	copied.Set(Lunch, meal("Curry"))
+
+	assert.Equal(t, []NamedMeal{{Breakfast, meal("Toast")}, {Lunch, meal("Soup")}, {Dinner, meal("Steak")}}, m.Meals)
+	assert.Equal(t, meal("Curry"), served(copied, Lunch))
+	_, ok := m.Meal(Formal)
+	assert.False(t, ok, "Meal missing from the menu found")
+}
+
+func TestMenu_UnmarshalJSON_Legacy(t *testing.T) {
+	var m Menu
+	require.NoError(t, json.Unmarshal([]byte(`{"Date":"2018-12-03T00:00:00Z","Lunch":["Soup"],"Dinner":[]}`), &m))
+
+	assert.Equal(t, testMonday, m.Date)
+	assert.Equal(t, []NamedMeal{{Lunch, meal("Soup")}, {Dinner, Meal{}}}, m.Meals)
+}
+
+func TestMealName(t *testing.T) {
+	for name, expected := range map[string]string{"lunch": Lunch, "Supper": Dinner, "formal": Formal, "BRUNCH": Brunch} {
+		actual, ok := MealName(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, expected, actual, name)
+	}
+
+	_, ok := MealName("elevenses")
+	assert.False(t, ok, "Unknown meal recognised")
+}
@@ -0,0 +1,125 @@
+package menus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// Rule is a post-processing step applied to the text of each scraped item.
+// A rule matches either the whole text exactly (ignoring case), or a regular expression.
+// When it matches, the item is dropped, or the replacement and tag are applied and processing continues with the next rule.
+type Rule struct {
+	Match   string  `json:"match,omitempty"`   // text to match exactly, ignoring case
+	Regexp  string  `json:"regexp,omitempty"`  // regular expression to match
+	Replace *string `json:"replace,omitempty"` // replacement for the whole text, or the matched part of a Regexp (which may use $1 etc.)
+	Drop    bool    `json:"drop,omitempty"`    // remove the item from the meal
+	Tag     Tag     `json:"tag,omitempty"`     // tag to add to the item
+
+	re *regexp.Regexp
+}
+
+// Rules is an ordered list of post-processing rules
+type Rules []Rule
+
+func replacement(s string) *string {
+	return &s
+}
+
+// DefaultRules are the rules used when none are configured
+var DefaultRules = Rules{
+	// Detect TBC
+	{Match: "tbc", Drop: true},
+	{Match: "to be confirmed", Drop: true},
+	// Correct Fish of the Day
+	{Match: "fod", Replace: replacement("Fish of the Day")},
+	{Match: "fish of the day", Replace: replacement("Fish of the Day")},
+}
+
+// LoadRules reads a JSON list of rules from the file at path
+func LoadRules(path string) (Rules, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules Rules
+	if err = json.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("Rules: %v", err)
+	}
+
+	return rules, rules.Compile()
+}
+
+// Compile checks every rule and compiles the regular expressions.
+// Rules containing a Regexp do not match anything until they are compiled.
+func (r Rules) Compile() error {
+	for i := range r {
+		if (r[i].Match == "") == (r[i].Regexp == "") {
+			return fmt.Errorf("Rules: rule %v must have exactly one of match or regexp", i+1)
+		}
+
+		if r[i].Regexp == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(r[i].Regexp)
+		if err != nil {
+			return fmt.Errorf("Rules: rule %v: %v", i+1, err)
+		}
+		r[i].re = re
+	}
+
+	return nil
+}
+
+// Apply processes the text of an item and parses its tags.
+// It returns false if the item should be dropped from the meal.
+func (r Rules) Apply(text string) (Item, bool) {
+	text = trimItem(text)
+	var tags []Tag
+
+	for _, rule := range r {
+		matched := false
+		switch {
+		case rule.Match != "":
+			matched = strings.EqualFold(text, rule.Match)
+			if matched && rule.Replace != nil {
+				text = *rule.Replace
+			}
+		case rule.re != nil:
+			matched = rule.re.MatchString(text)
+			if matched && rule.Replace != nil {
+				text = trimItem(rule.re.ReplaceAllString(text, *rule.Replace))
+			}
+		}
+
+		if !matched {
+			continue
+		}
+
+		if rule.Drop {
+			return Item{}, false
+		}
+		if rule.Tag != "" {
+			tags = append(tags, rule.Tag)
+		}
+	}
+
+	if text == "" {
+		return Item{}, false
+	}
+
+	item := parseItem(text)
+	for _, t := range tags {
+		item.addTag(t)
+	}
+
+	return item, true
+}
+
+func trimItem(text string) string {
+	return strings.Trim(text, "\xa0 \n\t")
+}
@@ -0,0 +1,95 @@
+package menus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ruleTest struct {
+	Case     string
+	Expected string // empty if the item is dropped
+}
+
+func defaultRuleCases() []ruleTest {
+	return []ruleTest{
+		// Invalid item tests
+		{"\xa0\n", ""},
+		{"  asdf\xa0\n", "asdf"},
+		// TBC tests
+		{"TBC", ""},
+		{"To be Confirmed", ""},
+		{"To be confirmed \n", ""},
+		{"to Be cONFIRmED", ""},
+		// Fish of the Day tests
+		{"Fish of the Day", "Fish of the Day"},
+		{"FoD", "Fish of the Day"},
+		{"fIsh OF ThE DAy", "Fish of the Day"},
+		// No post processing tests
+		{"Beef Steak", "Beef Steak"},
+		{"SoME WEIrd fOOd", "SoME WEIrd fOOd"},
+	}
+}
+
+func testRules(t *testing.T, rules Rules, cases []ruleTest) {
+	for _, rt := range cases {
+		item, ok := rules.Apply(rt.Case)
+		if rt.Expected == "" {
+			assert.False(t, ok, "Item %q not dropped", rt.Case)
+		} else {
+			assert.True(t, ok, "Item %q dropped", rt.Case)
+			assert.Equal(t, rt.Expected, item.String(), "Incorrect post processing")
+		}
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	testRules(t, DefaultRules, defaultRuleCases())
+}
+
+func TestLoadRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rules.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[
+		{"match": "tbc", "drop": true},
+		{"regexp": "(?i)^(fod|fish of the day)$", "replace": "Fish of the Day"},
+		{"regexp": "\\s*\\*+$", "replace": ""},
+		{"regexp": "(?i)\\bbrocolli\\b", "replace": "Broccoli"},
+		{"regexp": "(?i)^allergens", "drop": true},
+		{"regexp": "(?i)halal", "tag": "H"}
+	]`), 0600))
+
+	rules, err := LoadRules(path)
+	require.NoError(t, err)
+
+	testRules(t, rules, []ruleTest{
+		{"TBC", ""},
+		{"fod", "Fish of the Day"},
+		{"Roast Chicken **", "Roast Chicken"},
+		{"Brocolli Bake (v)", "Broccoli Bake (V)"},
+		{"Allergens available on request", ""},
+		{"Halal Lamb Curry", "Halal Lamb Curry (H)"},
+		{"Beef Steak", "Beef Steak"},
+	})
+}
+
+func TestLoadRules_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, contents := range []string{`[{"drop": true}]`, `[{"match": "a", "regexp": "b"}]`, `[{"regexp": "("}]`, `{}`} {
+		path := filepath.Join(dir, "rules.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+
+		_, err = LoadRules(path)
+		assert.Error(t, err, "Invalid rules %v loaded", contents)
+	}
+}
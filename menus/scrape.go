@@ -6,7 +6,9 @@ import (
 
 	"net/http"
 
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/yhat/scrape"
 	"golang.org/x/net/html"
@@ -14,136 +16,325 @@ import (
 )
 
 const (
-	menuURL    = "https://www.chu.cam.ac.uk/student-hub/catering/menus/"
 	maxRetries = 5
 )
 
 func getRootNode(url string) (*html.Node, error) {
 	res, err := http.Get(url)
 	if err != nil {
-		return nil, err
+		return nil, &NetworkError{URL: url, Err: err}
 	}
 	defer res.Body.Close()
 
-	return html.Parse(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return nil, &NetworkError{URL: url, Err: fmt.Errorf("HTTP status %v", res.Status)}
+	}
+
+	root, err := html.Parse(res.Body)
+	if err != nil {
+		return nil, &NetworkError{URL: url, Err: err}
+	}
+
+	return root, nil
 }
 
-func makeTableError(num int) error {
-	return fmt.Errorf("Scraper: Incorrect number of tables scraped (Expected: 1, Actual: %v)", num)
+// Returns the cells of a table row, repeating cells which span multiple columns
+func cells(row *html.Node) []*html.Node {
+	var ret []*html.Node
+	for c := row.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom != atom.Td && c.DataAtom != atom.Th {
+			continue
+		}
+
+		span, err := strconv.Atoi(scrape.Attr(c, "colspan"))
+		if err != nil || span < 1 {
+			span = 1
+		}
+		for i := 0; i < span; i++ {
+			ret = append(ret, c)
+		}
+	}
+
+	return ret
 }
 
-func getTableNode(root *html.Node) (*html.Node, error) {
-	tables := scrape.FindAll(root, scrape.ByTag(atom.Table))
-	// Consistency Check
-	if len(tables) == 0 {
-		return nil, makeTableError(0)
-	} else if len(tables) != 1 {
-		return tables[0], makeTableError(len(tables))
+// Returns the meal a header cell refers to, or "" if it is not a meal header.
+// Header cells start with the name of the meal, e.g. "Lunch", "Dinner (17:45 - 19:15)" or "Formal Hall".
+func mealHeader(cell *html.Node) string {
+	if _, ok := scrape.Find(cell, scrape.ByTag(atom.Li)); ok {
+		return ""
 	}
-	return tables[0], nil
+
+	fields := strings.Fields(strings.ToLower(scrape.Text(cell)))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	name, _ := MealName(strings.Trim(fields[0], ",.:"))
+	return name
 }
 
-// This is a wrapper function for the above
-func getTable() (*html.Node, error) {
-	root, err := getRootNode(menuURL)
-	if err != nil {
-		return nil, err
+// Reports whether a cell starts with the name of a weekday
+func isDayCell(cell *html.Node) bool {
+	fields := strings.Fields(strings.ToLower(scrape.Text(cell)))
+	if len(fields) == 0 {
+		return false
 	}
 
-	return getTableNode(root)
+	_, ok := matchName(strings.Trim(fields[0], ",.:"), weekdayNames[:])
+	return ok
 }
 
-func postProcessing(item string) string {
-	item = strings.Trim(item, "\xa0 \n\t")
-	item_lower := strings.ToLower(item)
+// Scores how much a table looks like the menu table
+func scoreTable(table *html.Node) int {
+	score := 0
+	for _, row := range scrape.FindAll(table, scrape.ByTag(atom.Tr)) {
+		for _, cell := range cells(row) {
+			if mealHeader(cell) != "" {
+				score += 2
+			} else if isDayCell(cell) {
+				score++
+			}
+		}
+	}
+
+	return score
+}
 
-	// Detect TBC
-	if item_lower == "tbc" || item_lower == "to be confirmed" {
-		return ""
+// Finds the menu table by its contents, since the page may contain other tables
+func getTableNode(root *html.Node) (*html.Node, Warnings, error) {
+	tables := scrape.FindAll(root, scrape.ByTag(atom.Table))
+	if len(tables) == 0 {
+		body, _ := scrape.Find(root, scrape.ByTag(atom.Body))
+		return nil, nil, &LayoutError{Reason: "No tables found", Snippet: snippet(body)}
 	}
 
-	// Correct Fish of the Day
-	if item_lower == "fod" || item_lower == "fish of the day" {
-		return "Fish of the Day"
+	var (
+		best       *html.Node
+		bestScore  int
+		candidates int
+	)
+
+	for _, table := range tables {
+		score := scoreTable(table)
+		if score > 0 {
+			candidates++
+		}
+		if score > bestScore {
+			best, bestScore = table, score
+		}
 	}
 
-	return item
-}
+	if best == nil {
+		return nil, nil, &LayoutError{Reason: fmt.Sprintf("No menu table found (%v tables)", len(tables)), Snippet: snippet(tables[0])}
+	}
 
-func parseMeal(node *html.Node) (Meal, error) {
-	if node == nil {
-		// Custom Error
-		return Meal{}, fmt.Errorf("Scraper: nil node passed into parseMeal")
+	var warnings Warnings
+	if candidates > 1 {
+		warnings = append(warnings, &LayoutError{Reason: fmt.Sprintf("%v tables look like menus, using the closest match", candidates), Snippet: snippet(best)})
 	}
 
+	return best, warnings, nil
+}
+
+func parseMeal(node *html.Node, rules Rules) Meal {
 	// Parses a list of meal items into a Meal
-	items := scrape.FindAll(node, scrape.ByTag(atom.Li))
+	// Cells without a list are treated as one item per line
+	var texts []string
+	if items := scrape.FindAll(node, scrape.ByTag(atom.Li)); len(items) != 0 {
+		for i := range items {
+			texts = append(texts, scrape.Text(items[i]))
+		}
+	} else {
+		for _, n := range scrape.FindAll(node, func(n *html.Node) bool { return n.Type == html.TextNode }) {
+			texts = append(texts, strings.Split(n.Data, "\n")...)
+		}
+	}
 
-	var meal Meal = make([]string, 0, len(items))
-	for i := range items {
-		if p := postProcessing(scrape.Text(items[i])); p != "" {
-			meal = append(meal, p)
+	meal := make(Meal, 0, len(texts))
+	for _, text := range texts {
+		if item, ok := rules.Apply(text); ok {
+			meal = append(meal, item)
 		}
 	}
 
-	return meal, nil
+	return meal
 }
 
-func parseDay(node *html.Node) (Menu, error) {
-	if node == nil {
-		// Custom Error
-		return Menu{}, fmt.Errorf("Scraper: nil node passed into parseDay")
+// Position of a meal's column in the menu table, -1 if it is missing
+type column struct {
+	meal  string
+	index int
+}
+
+// Positions of the columns in the menu table
+type layout struct {
+	date  int
+	meals []column // in serving order
+}
+
+// The layout of the table when it has no header
+var defaultLayout = layout{date: 0, meals: []column{{Lunch, 1}, {Dinner, 2}}}
+
+// Finds the header row naming the meal columns, and returns its index and the layout of the table.
+// Lunch and dinner are expected in every table, so are included in the layout even if their columns are missing.
+func parseHeader(rows []*html.Node) (int, layout, bool) {
+	for i, row := range rows {
+		found := make(map[string]int)
+		for j, cell := range cells(row) {
+			if meal := mealHeader(cell); meal != "" {
+				if _, ok := found[meal]; !ok {
+					found[meal] = j
+				}
+			}
+		}
+
+		if len(found) == 0 {
+			continue
+		}
+
+		var l layout
+		for _, meal := range MealNames {
+			index, ok := found[meal]
+			if !ok {
+				if meal != Lunch && meal != Dinner {
+					continue
+				}
+				index = -1
+			}
+			l.meals = append(l.meals, column{meal, index})
+		}
+
+		// The dates are in the first column which is not a meal
+		for l.isMeal(l.date) {
+			l.date++
+		}
+		return i, l, true
+	}
+
+	return -1, defaultLayout, false
+}
+
+// Reports whether the column at index contains a meal
+func (l layout) isMeal(index int) bool {
+	for _, c := range l.meals {
+		if c.index == index {
+			return true
+		}
 	}
-	// Parses a day's menu into a Menu
-	unparsedMeal := scrape.FindAll(node, scrape.ByTag(atom.Td))
-	if len(unparsedMeal) != 3 {
-		return Menu{}, fmt.Errorf("Scraper: Incorrect number of columns in table (Expected: 3, Actual: %v)", len(unparsedMeal))
+
+	return false
+}
+
+// Parses a row of the table into a Menu, returning false if the row does not contain a menu.
+// Any problems are returned as LayoutErrors for the row numbered rowNum.
+func parseDay(row *html.Node, rowNum int, l layout, ref time.Time, rules Rules) (Menu, Warnings, bool) {
+	cs := cells(row)
+	if len(cs) <= l.date {
+		return Menu{}, Warnings{&LayoutError{Row: rowNum, Reason: "no date column", Snippet: snippet(row)}}, false
 	}
 
-	menu := Menu{}
-	meal, err := parseMeal(unparsedMeal[1])
+	date, err := ParseDate(scrape.Text(cs[l.date]), ref)
 	if err != nil {
-		return menu, err
+		return Menu{}, Warnings{&LayoutError{Row: rowNum, Reason: strings.TrimPrefix(err.Error(), "Date: "), Snippet: snippet(row)}}, false
 	}
-	menu.Lunch = meal
 
-	meal, err = parseMeal(unparsedMeal[2])
-	if err != nil {
-		return menu, err
+	menu := Menu{Date: date}
+	var warnings Warnings
+	var spanned []string
+	for _, c := range l.meals {
+		if c.index < 0 || c.index >= len(cs) {
+			warnings = append(warnings, &LayoutError{Row: rowNum, Reason: fmt.Sprintf("no %v column", strings.ToLower(c.meal)), Snippet: snippet(row), Date: date, Meals: []string{c.meal}})
+			menu.Set(c.meal, Meal{})
+			continue
+		}
+
+		// A date cell spanning the meal columns, e.g. "Wednesday - Hall closed", is a note rather than a menu
+		if cs[c.index] == cs[l.date] {
+			spanned = append(spanned, c.meal)
+			menu.Set(c.meal, Meal{})
+			continue
+		}
+		menu.Set(c.meal, parseMeal(cs[c.index], rules))
 	}
-	menu.Dinner = meal
 
-	return menu, nil
+	if len(spanned) != 0 {
+		warnings = append(warnings, &LayoutError{Row: rowNum, Reason: "date cell spans the meal columns, meals left empty", Snippet: snippet(row), Date: date, Meals: spanned})
+	}
+
+	return menu, warnings, true
 }
 
-func parseWeek(node *html.Node) ([]Menu, error) {
+// Parses the whole weeks menu and returns the menus keyed by date.
+// Rows which cannot be parsed are skipped with a warning, and an error is only returned if no menus are found.
+// ref is used to fill in parts of dates which are missing from the table, and rules are applied to every item.
+func parseWeek(node *html.Node, ref time.Time, rules Rules) (Week, Warnings, error) {
 	if node == nil {
 		// Custom Error
-		return []Menu{}, fmt.Errorf("Scraper: nil node passed into parseWeek")
+		return Week{}, nil, fmt.Errorf("Scraper: nil node passed into parseWeek")
 	}
-	// Parses the whole weeks menu and returns a slice of menu structs
-	unparsedMenus := scrape.FindAll(node, scrape.ByTag(atom.Tr))
-	if len(unparsedMenus) != 8 {
-		return []Menu{}, fmt.Errorf("Scraper: Incorrect number of rows in table (Expected: 8, Actual: %v)", len(unparsedMenus))
+
+	rows := scrape.FindAll(node, scrape.ByTag(atom.Tr))
+	var warnings Warnings
+
+	header, l, ok := parseHeader(rows)
+	if !ok {
+		warnings = append(warnings, &LayoutError{Reason: "no meal header, assuming date, lunch and dinner columns", Snippet: snippet(node)})
 	}
 
-	week := make([]Menu, 7)
-	for i, menu := range unparsedMenus[1:] {
-		day, err := parseDay(menu)
-		if err != nil {
-			return week, err
+	week := make(Week, 7)
+	for i, row := range rows[header+1:] {
+		rowNum := header + i + 2
+		if len(cells(row)) == 0 || strings.TrimSpace(scrape.Text(row)) == "" {
+			continue
+		}
+
+		day, dayWarnings, ok := parseDay(row, rowNum, l, ref, rules)
+		warnings = append(warnings, dayWarnings...)
+		if !ok {
+			continue
 		}
-		week[i] = day
+
+		if _, ok := week.Day(day.Date); ok {
+			warnings = append(warnings, &LayoutError{Row: rowNum, Reason: fmt.Sprintf("duplicate menu for %v", DateKey(day.Date)), Snippet: snippet(row)})
+			continue
+		}
+		week[DateKey(day.Date)] = day
 	}
 
-	return week, nil
+	if len(week) == 0 {
+		return week, warnings, &EmptyTableError{Snippet: snippet(node)}
+	}
+
+	// Report the days missing from the week (Monday to Sunday) containing the first menu
+	first := week.Days()[0].Date
+	monday := first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+	for i := 0; i < 7; i++ {
+		date := monday.AddDate(0, 0, i)
+		if _, ok := week.Day(date); !ok {
+			warnings = append(warnings, &DayMissingError{Date: date, Snippet: snippet(node)})
+		}
+	}
+
+	return week, warnings, nil
 }
 
-func benchmark(root *html.Node) ([]Menu, error) {
-	table, err := getTableNode(root)
+// Parses the week's menus from the root of a menu page.
+// If parts of the page could not be parsed, the week is returned with Warnings as the error.
+func parsePage(root *html.Node, ref time.Time, rules Rules) (Week, error) {
+	table, warnings, err := getTableNode(root)
+	if err != nil {
+		return nil, err
+	}
+
+	week, weekWarnings, err := parseWeek(table, ref, rules)
 	if err != nil {
 		return nil, err
 	}
 
-	return parseWeek(table)
+	if warnings = append(warnings, weekWarnings...); len(warnings) != 0 {
+		return week, warnings
+	}
+
+	return week, nil
 }
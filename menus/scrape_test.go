@@ -1,38 +1,164 @@
 package menus
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
 )
 
-type postProcessingTest struct {
-	Case     string
-	Expected string
-}
-
-func postProcessingCases() []postProcessingTest {
-	return []postProcessingTest{
-		// Invalid item tests
-		{"\xa0\n", ""},
-		{"  asdf\xa0\n", "asdf"},
-		// TBC tests
-		{"TBC", ""},
-		{"To be Confirmed", ""},
-		{"To be confirmed \n", ""},
-		{"to Be cONFIRmED", ""},
-		// Fish of the Day tests
-		{"Fish of the Day", "Fish of the Day"},
-		{"FoD", "Fish of the Day"},
-		{"fIsh OF ThE DAy", "Fish of the Day"},
-		// No post processing tests
-		{"Beef Steak", "Beef Steak"},
-		{"SoME WEIrd fOOd", "SoME WEIrd fOOd"},
+func parseTestPage(t *testing.T, page string) (Week, error) {
+	root, err := html.Parse(strings.NewReader(page))
+	require.NoError(t, err)
+
+	return parsePage(root, testMonday, DefaultRules)
+}
+
+func dayRow(day, lunch, dinner string) string {
+	return "<tr><td>" + day + "</td><td><ul><li>" + lunch + "</li></ul></td><td><ul><li>" + dinner + "</li></ul></td></tr>"
+}
+
+func fullWeek() string {
+	var rows []string
+	for _, m := range Week(testWeek()).Days() {
+		rows = append(rows, dayRow(m.Date.Format("Monday 2 January"), served(m, Lunch)[0].Name, served(m, Dinner)[0].Name))
+	}
+
+	return strings.Join(rows, "")
+}
+
+const header = "<tr><th></th><th>Lunch</th><th>Dinner</th></tr>"
+
+func TestParsePage(t *testing.T) {
+	week, err := parseTestPage(t, "<table>"+header+fullWeek()+"</table>")
+	assert.NoError(t, err)
+	assert.Equal(t, Week(testWeek()), week)
+}
+
+func TestParsePage_OtherTables(t *testing.T) {
+	page := "<table><tr><td>Opening hours</td><td>9-5</td></tr></table><table>" + header + fullWeek() + "</table>"
+	week, err := parseTestPage(t, page)
+	assert.NoError(t, err)
+	assert.Equal(t, Week(testWeek()), week)
+}
+
+func TestParsePage_SwappedColumns(t *testing.T) {
+	page := "<table><tr><th>Dinner</th><th>Lunch</th><th>Day</th><th>Notes</th></tr>" +
+		"<tr><td>Steak</td><td>Soup</td><th>Monday</th><td>-</td></tr></table>"
+	week, err := parseTestPage(t, page)
+
+	assert.IsType(t, Warnings{}, err, "Missing days not reported")
+	m, ok := week.Day(testMonday)
+	assert.True(t, ok, "Monday not parsed")
+	assert.Equal(t, meal("Soup"), served(m, Lunch))
+	assert.Equal(t, meal("Steak"), served(m, Dinner))
+}
+
+func TestParsePage_OtherMeals(t *testing.T) {
+	page := "<table><tr><th>Day</th><th>Formal Hall</th><th>Breakfast</th><th>Lunch</th><th>Dinner</th></tr>" +
+		"<tr><td>Monday</td><td>Duck</td><td>Toast</td><td>Soup</td><td>Steak</td></tr></table>"
+	week, err := parseTestPage(t, page)
+
+	assert.IsType(t, Warnings{}, err, "Missing days not reported")
+	m, ok := week.Day(testMonday)
+	require.True(t, ok, "Monday not parsed")
+	assert.Equal(t, []NamedMeal{{Breakfast, meal("Toast")}, {Lunch, meal("Soup")}, {Dinner, meal("Steak")}, {Formal, meal("Duck")}}, m.Meals)
+}
+
+func TestParsePage_PartialWeek(t *testing.T) {
+	page := "<table><tr><th>Day</th><th>Lunch</th></tr>" +
+		"<tr><td>Monday</td><td>Soup<br>Bread</td></tr>" +
+		"<tr><td>Not a day</td><td>Soup</td></tr>" +
+		"<tr><td colspan=\"2\">Tuesday</td></tr></table>"
+	week, err := parseTestPage(t, page)
+
+	require.IsType(t, Warnings{}, err, "Partial week not reported")
+	assert.Len(t, week, 2)
+	assert.Equal(t, meal("Soup", "Bread"), served(week[DateKey(testMonday)], Lunch))
+	assert.Empty(t, served(week[DateKey(testMonday)], Dinner))
+
+	// The date cell spanning the lunch column is not its contents
+	tuesday, ok := week.Day(testMonday.AddDate(0, 0, 1))
+	require.True(t, ok, "Tuesday not parsed")
+	assert.Equal(t, []NamedMeal{{Lunch, Meal{}}, {Dinner, Meal{}}}, tuesday.Meals)
+
+	rows := map[int]bool{}
+	missing := 0
+	for _, w := range err.(Warnings) {
+		switch e := w.(type) {
+		case *LayoutError:
+			rows[e.Row] = true
+			assert.NotEmpty(t, e.Snippet, "Layout error without HTML")
+		case *DayMissingError:
+			missing++
+		}
 	}
+	assert.True(t, rows[3], "Unparseable row not reported")
+	assert.True(t, rows[4], "Spanned row not reported")
+	assert.Equal(t, 5, missing, "Missing days not reported")
+}
+
+func TestParsePage_NoHeader(t *testing.T) {
+	week, err := parseTestPage(t, "<table>"+fullWeek()+"</table>")
+	assert.IsType(t, Warnings{}, err, "Missing header not reported")
+	assert.Equal(t, Week(testWeek()), week)
+}
+
+func TestParsePage_NoMenu(t *testing.T) {
+	_, err := parseTestPage(t, "<p>Closed</p>")
+	assert.IsType(t, &LayoutError{}, err, "Missing table not reported")
+
+	_, err = parseTestPage(t, "<table><tr><td>Opening hours</td></tr></table>")
+	assert.IsType(t, &LayoutError{}, err, "Missing menu table not reported")
+
+	_, err = parseTestPage(t, "<table>"+header+"</table>")
+	assert.IsType(t, &EmptyTableError{}, err, "Empty table not reported")
 }
 
-func TestPostProcessing(t *testing.T) {
-	for _, ppt := range postProcessingCases() {
-		assert.Equal(t, ppt.Expected, postProcessing(ppt.Case), "Incorrect post processing")
+// Compares the parsed result of each page in testdata with the fixture recorded next to it.
+// Fixtures are recorded with chumenu/record.
+func TestParsePage_Fixtures(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "*.html"))
+	require.NoError(t, err)
+	require.NotEmpty(t, pages, "No fixtures found")
+
+	for _, page := range pages {
+		b, err := ioutil.ReadFile(strings.TrimSuffix(page, ".html") + ".json")
+		if !assert.NoError(t, err, "Fixture for %v not recorded", page) {
+			continue
+		}
+
+		var expected Fixture
+		require.NoError(t, json.Unmarshal(b, &expected), page)
+
+		actual, err := RecordFixture(page, expected.Ref, DefaultRules)
+		require.NoError(t, err, page)
+
+		actualJSON, err := json.MarshalIndent(actual, "", "  ")
+		require.NoError(t, err, page)
+		assert.JSONEq(t, string(b), string(actualJSON), "Parsed result of %v differs from fixture", page)
 	}
 }
+
+// Without a reference time, partial dates on a saved page are completed from the clock
+func TestFile_Now(t *testing.T) {
+	page := filepath.Join("testdata", "week_2018-12-03.html")
+	ref := time.Date(2018, time.December, 3, 12, 0, 0, 0, time.UTC)
+
+	expected, err := File{Path: page, Ref: ref}.Week()
+	require.NoError(t, err)
+
+	week, err := File{Path: page, Now: func() time.Time { return ref }}.Week()
+	require.NoError(t, err)
+	assert.Equal(t, expected, week)
+
+	// A year later the weekdays no longer match the dates
+	week, _ = File{Path: page, Now: func() time.Time { return ref.AddDate(1, 0, 0) }}.Week()
+	assert.NotEqual(t, expected, week, "Clock not used")
+}
@@ -0,0 +1,92 @@
+package menus
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Match is an item in a meal which matched a search
+type Match struct {
+	Date time.Time
+	Meal string
+	Item Item
+}
+
+// Search returns the items in week which match query, in date order.
+// Matching is case-insensitive and tolerates small spelling mistakes: every word of the query must either
+// be the start of a word in the item, or be within one edit of a word in the item if it has at least 4 letters.
+func Search(week Week, query string) []Match {
+	terms := words(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	for _, m := range week.Days() {
+		for _, meal := range m.Meals {
+			for _, item := range meal.Items {
+				if matchTerms(words(item.Name), terms) {
+					matches = append(matches, Match{Date: m.Date, Meal: meal.Name, Item: item})
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func matchTerms(item, terms []string) bool {
+	for _, t := range terms {
+		found := false
+		for _, w := range item {
+			if strings.HasPrefix(w, t) || (len(t) >= 4 && editDistance(w, t) <= 1) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := range s {
+		cur[0] = i + 1
+		for j := range t {
+			cost := 1
+			if s[i] == t[j] {
+				cost = 0
+			}
+			cur[j+1] = minInt(minInt(prev[j+1]+1, cur[j]+1), prev[j]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(t)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,46 @@
+package menus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type matchTest struct {
+	Item     string
+	Query    string
+	Expected bool
+}
+
+func matchCases() []matchTest {
+	return []matchTest{
+		{"Chicken Tikka Masala", "tikka", true},
+		{"Chicken Tikka Masala", "CHICKEN masala", true},
+		{"Chicken Tikka Masala", "chick", true},
+		{"Beef Lasagne", "lasagna", true},
+		{"Vegetable Curry (V)", "cury", true},
+		{"Beef Lasagne", "pork", false},
+		{"Beef Lasagne", "beef curry", false},
+		{"Pea Soup", "pie", false},
+		{"Pea Soup", "", false},
+	}
+}
+
+func TestSearch_Matching(t *testing.T) {
+	for _, mt := range matchCases() {
+		week := NewWeek(Menu{Date: testMonday, Meals: []NamedMeal{{Lunch, meal(mt.Item)}}})
+		assert.Equal(t, mt.Expected, len(Search(week, mt.Query)) == 1, "Incorrect match of %q in %q", mt.Query, mt.Item)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	week := Week(testWeek())
+	tuesday := testMonday.AddDate(0, 0, 1)
+	m := week[DateKey(tuesday)]
+	m.Set(Lunch, meal("Chicken Curry", "Rice"))
+	week[DateKey(tuesday)] = m
+
+	assert.Equal(t, []Match{{tuesday, "Lunch", Item{Name: "Chicken Curry"}}}, Search(week, "curry"))
+	assert.Len(t, Search(week, "dinner"), 7, "Matches missing")
+	assert.Empty(t, Search(week, "lasagne"))
+}
@@ -0,0 +1,93 @@
+package menus
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// ChurchillURL is the address of the Churchill College menu page
+const ChurchillURL = "https://www.chu.cam.ac.uk/student-hub/catering/menus/"
+
+// Source is implemented by anything which can provide the dated menus for a week (Monday to Sunday)
+type Source interface {
+	Week() (Week, error)
+}
+
+// Scraper is a Source which downloads and parses the menu table from a web page
+type Scraper struct {
+	URL      string
+	Rules    Rules            // post-processing rules, DefaultRules if nil
+	Location *time.Location   // time zone of the menu's dates, time.Local if nil
+	Now      func() time.Time // current time, used to complete partial dates (time.Now if nil)
+}
+
+// Week scrapes the menus for the week from the page at s.URL
+func (s Scraper) Week() (Week, error) {
+	root, err := getRootNode(s.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePage(root, nowIn(s.Now, s.Location), rulesOrDefault(s.Rules))
+}
+
+// File is a Source which parses a copy of the menu page saved to disk
+type File struct {
+	Path     string
+	Rules    Rules            // post-processing rules, DefaultRules if nil
+	Ref      time.Time        // time the page was saved, used to complete partial dates (now if zero)
+	Location *time.Location   // time zone of the menu's dates when Ref is zero, time.Local if nil
+	Now      func() time.Time // current time when Ref is zero (time.Now if nil)
+}
+
+// Week parses the menus for the week from the page stored at f.Path
+func (f File) Week() (Week, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	root, err := html.Parse(file)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := f.Ref
+	if ref.IsZero() {
+		ref = nowIn(f.Now, f.Location)
+	}
+
+	return parsePage(root, ref, rulesOrDefault(f.Rules))
+}
+
+// Returns the current time from now in loc, so that the week containing today is chosen by the venue's calendar
+func nowIn(now func() time.Time, loc *time.Location) time.Time {
+	if now == nil {
+		now = time.Now
+	}
+
+	if loc == nil {
+		return now()
+	}
+
+	return now().In(loc)
+}
+
+func rulesOrDefault(rules Rules) Rules {
+	if rules == nil {
+		return DefaultRules
+	}
+
+	return rules
+}
+
+// Static is a Source which always returns the same menus
+type Static Week
+
+// Week returns the menus held in s
+func (s Static) Week() (Week, error) {
+	return Week(s), nil
+}
@@ -0,0 +1,140 @@
+package menus
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Tag is a dietary or allergen marker on a food item
+type Tag string
+
+// Recognised tags
+const (
+	Vegetarian   Tag = "V"
+	Vegan        Tag = "VG"
+	GlutenFree   Tag = "GF"
+	DairyFree    Tag = "DF"
+	ContainsNuts Tag = "N"
+)
+
+// Markers used on the menu page for each tag (lower case)
+var tagMarkers = map[string]Tag{
+	"v":             Vegetarian,
+	"veg":           Vegetarian,
+	"vegetarian":    Vegetarian,
+	"vg":            Vegan,
+	"ve":            Vegan,
+	"vegan":         Vegan,
+	"gf":            GlutenFree,
+	"gluten free":   GlutenFree,
+	"df":            DairyFree,
+	"dairy free":    DairyFree,
+	"n":             ContainsNuts,
+	"nuts":          ContainsNuts,
+	"contains nuts": ContainsNuts,
+}
+
+var (
+	bracketed = regexp.MustCompile(`\s*\(([^()]*)\)`)
+	separator = regexp.MustCompile(`\s*[,/&]\s*`)
+)
+
+// Item is a single food item and its dietary tags
+type Item struct {
+	Name string
+	Tags []Tag `json:",omitempty"`
+}
+
+func (i Item) String() string {
+	if len(i.Tags) == 0 {
+		return i.Name
+	}
+
+	tags := make([]string, len(i.Tags))
+	for j, t := range i.Tags {
+		tags[j] = string(t)
+	}
+
+	return i.Name + " (" + strings.Join(tags, ", ") + ")"
+}
+
+// Has reports whether the item is tagged with t
+func (i Item) Has(t Tag) bool {
+	for _, tag := range i.Tags {
+		if tag == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UnmarshalJSON accepts either an Item object or a plain string, which is how items were stored before tags
+func (i *Item) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*i = Item{Name: name}
+		return nil
+	}
+
+	type item Item
+	return json.Unmarshal(b, (*item)(i))
+}
+
+func (i *Item) addTag(t Tag) {
+	if !i.Has(t) {
+		i.Tags = append(i.Tags, t)
+	}
+}
+
+// Parses the dietary markers out of the text of an item, e.g. "Vegetable Curry (V, GF)" or "Nut Roast VG N"
+func parseItem(text string) Item {
+	item := Item{}
+
+	// Bracketed markers anywhere in the text
+	text = bracketed.ReplaceAllStringFunc(text, func(group string) string {
+		tags, ok := parseMarkers(separator.Split(strings.ToLower(bracketed.FindStringSubmatch(group)[1]), -1))
+		if !ok {
+			return group
+		}
+
+		for _, t := range tags {
+			item.addTag(t)
+		}
+		return ""
+	})
+
+	// Upper case markers at the end of the text
+	words := strings.Fields(text)
+	end := len(words)
+	for end > 1 {
+		last := strings.Trim(words[end-1], ",")
+		if _, ok := tagMarkers[strings.ToLower(last)]; !ok || last != strings.ToUpper(last) {
+			break
+		}
+		end--
+	}
+
+	for _, w := range words[end:] {
+		item.addTag(tagMarkers[strings.ToLower(strings.Trim(w, ","))])
+	}
+	words = words[:end]
+
+	item.Name = strings.TrimRight(strings.Join(words, " "), " ,-")
+	return item
+}
+
+// Returns the tags for a list of markers, or false if any of them is not a marker
+func parseMarkers(markers []string) ([]Tag, bool) {
+	tags := make([]Tag, 0, len(markers))
+	for _, m := range markers {
+		t, ok := tagMarkers[strings.TrimSpace(m)]
+		if !ok {
+			return nil, false
+		}
+		tags = append(tags, t)
+	}
+
+	return tags, len(tags) != 0
+}
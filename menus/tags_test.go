@@ -0,0 +1,43 @@
+package menus
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parseItemTest struct {
+	Case     string
+	Expected Item
+}
+
+func parseItemCases() []parseItemTest {
+	return []parseItemTest{
+		// Bracketed markers
+		{"Vegetable Curry (v)", Item{"Vegetable Curry", []Tag{Vegetarian}}},
+		{"Vegetable Curry (VG, GF)", Item{"Vegetable Curry", []Tag{Vegan, GlutenFree}}},
+		{"Bean Chilli (vg/gf) with Rice", Item{"Bean Chilli with Rice", []Tag{Vegan, GlutenFree}}},
+		{"Pesto Pasta (V) (contains nuts)", Item{"Pesto Pasta", []Tag{Vegetarian, ContainsNuts}}},
+		// Trailing markers
+		{"Nut Roast VG N", Item{"Nut Roast", []Tag{Vegan, ContainsNuts}}},
+		{"Jacket Potato - GF", Item{"Jacket Potato", []Tag{GlutenFree}}},
+		// Text which is not a marker
+		{"Fish (Cod or Haddock)", Item{"Fish (Cod or Haddock)", nil}},
+		{"Vitamin Salad v", Item{"Vitamin Salad v", nil}},
+		{"VG", Item{"VG", nil}},
+		{"Beef Steak", Item{"Beef Steak", nil}},
+	}
+}
+
+func TestParseItem(t *testing.T) {
+	for _, pit := range parseItemCases() {
+		assert.Equal(t, pit.Expected, parseItem(pit.Case), "Incorrect tags parsed from %q", pit.Case)
+	}
+}
+
+func TestItem_UnmarshalJSON(t *testing.T) {
+	var m Meal
+	assert.NoError(t, json.Unmarshal([]byte(`["Beef Steak", {"Name": "Curry", "Tags": ["VG"]}]`), &m))
+	assert.Equal(t, Meal{{"Beef Steak", nil}, {"Curry", []Tag{Vegan}}}, m)
+}
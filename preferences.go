@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/ratorx/chumenu-go/menus"
+)
+
+const (
+	preferenceBucket = "preferences"
+
+	// Diet messages
+	dietSuccess = "Diet set to %s."
+	dietCurrent = "Current diet: %s.\nChange it with *diet vegetarian*, *diet vegan* or *diet none*."
+	dietInvalid = "Diet not recognised. Use *diet vegetarian*, *diet vegan* or *diet none*."
+	dietHidden  = "(%d items hidden by %s diet)"
+)
+
+type diet string
+
+// Supported diets
+const (
+	noDiet     diet = "none"
+	vegetarian diet = "vegetarian"
+	vegan      diet = "vegan"
+)
+
+// filter returns the items in m which are suitable for the diet, and the number of items removed
+func (d diet) filter(m menus.Meal) (menus.Meal, int) {
+	var keep func(menus.Item) bool
+
+	switch d {
+	case vegetarian:
+		keep = func(i menus.Item) bool { return i.Has(menus.Vegetarian) || i.Has(menus.Vegan) }
+	case vegan:
+		keep = func(i menus.Item) bool { return i.Has(menus.Vegan) }
+	default:
+		return m, 0
+	}
+
+	filtered := m.Filter(keep)
+	return filtered, len(m) - len(filtered)
+}
+
+// render formats a meal with a heading, filtered for the diet
+func (d diet) render(prefix string, m menus.Meal) string {
+	filtered, hidden := d.filter(m)
+	text := prefix + "\n" + filtered.String()
+	if hidden != 0 {
+		text += "\n" + fmt.Sprintf(dietHidden, hidden, d)
+	}
+
+	return text
+}
+
+// preferences are the per-user settings which apply to every message sent to the user
+type preferences struct {
+	Diet  diet   `json:",omitempty"`
+	Venue string `json:",omitempty"` // name of the chosen venue, the default venue if empty
+}
+
+func defaultPreferences() preferences {
+	return preferences{Diet: noDiet}
+}
+
+// Returns the preferences for sender from b, or the defaults if sender has not set any
+func loadPreferences(b *bolt.Bucket, sender []byte) (preferences, error) {
+	p := defaultPreferences()
+
+	v := b.Get(sender)
+	if v == nil {
+		return p, nil
+	}
+
+	err := json.Unmarshal(v, &p)
+	return p, err
+}
+
+func getPreferences(sender string) preferences {
+	var p preferences
+
+	err := cfg.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(preferenceBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", preferenceBucket)
+		}
+
+		var err error
+		p, err = loadPreferences(b, []byte(sender))
+		return err
+	})
+
+	if err != nil {
+		cfg.debug.Print(err)
+		return defaultPreferences()
+	}
+
+	return p
+}
+
+// updatePreferences applies update to the stored preferences of sender
+func updatePreferences(sender string, update func(*preferences)) error {
+	s := []byte(sender)
+
+	return cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(preferenceBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", preferenceBucket)
+		}
+
+		p, err := loadPreferences(b, s)
+		if err != nil {
+			return err
+		}
+		update(&p)
+
+		v, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(s, v)
+	})
+}
+
+func dietHandler(sender, text string) {
+	d := diet(text)
+	switch d {
+	case "":
+		responseMessage(sender, fmt.Sprintf(dietCurrent, getPreferences(sender).Diet), standardQR)
+		return
+	case "vegetarian", "veggie", "v":
+		d = vegetarian
+	case "vegan", "vg":
+		d = vegan
+	case noDiet, "off":
+		d = noDiet
+	default:
+		responseMessage(sender, dietInvalid, standardQR)
+		return
+	}
+
+	if err := updatePreferences(sender, func(p *preferences) { p.Diet = d }); err != nil {
+		cfg.debug.Print(err)
+		responseMessage(sender, unexpected, standardQR)
+		return
+	}
+
+	responseMessage(sender, fmt.Sprintf(dietSuccess, d), standardQR)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ratorx/chumenu-go/menus"
+	"github.com/ratorx/chumenu-go/timeofday"
+)
+
+// maxLookahead is the number of days searched for the next time a meal is served
+const maxLookahead = 14
+
+// schedule gives the time of a meal on any date.
+// Date overrides take precedence over weekday overrides, which take precedence over the default.
+// A nil time means the meal is not served.
+type schedule struct {
+	Default  *mealTime
+	Weekdays map[time.Weekday]*mealTime
+	Dates    map[string]*mealTime // keyed by menus.DateKey
+}
+
+// everyDay returns a schedule with the same time every day
+func everyDay(mt mealTime) schedule {
+	return schedule{Default: &mt}
+}
+
+// on returns the time of the meal on the date of t, and false if it is not served then
+func (s schedule) on(t time.Time) (mealTime, bool) {
+	mt := s.Default
+	if w, ok := s.Weekdays[t.Weekday()]; ok {
+		mt = w
+	}
+	if d, ok := s.Dates[menus.DateKey(t)]; ok {
+		mt = d
+	}
+
+	if mt == nil {
+		return mealTime{}, false
+	}
+	return *mt, true
+}
+
+// next returns the first date from the date of t on which the meal is served
+func (s schedule) next(t time.Time) (time.Time, mealTime, bool) {
+	for i := 0; i < maxLookahead; i++ {
+		date := t.AddDate(0, 0, i)
+		if mt, ok := s.on(date); ok {
+			return date, mt, true
+		}
+	}
+
+	return time.Time{}, mealTime{}, false
+}
+
+// serves reports whether the meal is served on any day
+func (s schedule) serves() bool {
+	if s.Default != nil {
+		return true
+	}
+
+	for _, mt := range s.Weekdays {
+		if mt != nil {
+			return true
+		}
+	}
+
+	for _, mt := range s.Dates {
+		if mt != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatTime(mt *mealTime) string {
+	if mt == nil {
+		return "Not served"
+	}
+	return mt.String()
+}
+
+// describe lists the usual times of the meal, followed by the overrides for dates from the date of t onwards
+func (s schedule) describe(t time.Time) string {
+	lines := []string{"Not usually served"}
+	if s.Default != nil {
+		lines[0] = s.Default.String()
+	}
+	for d := time.Monday; d < time.Monday+7; d++ {
+		weekday := d % 7
+		if mt, ok := s.Weekdays[weekday]; ok {
+			lines = append(lines, fmt.Sprintf("%s: %s", weekday, formatTime(mt)))
+		}
+	}
+
+	var dates []string
+	for key := range s.Dates {
+		if key >= menus.DateKey(t) {
+			dates = append(dates, key)
+		}
+	}
+	sort.Strings(dates)
+
+	for _, key := range dates {
+		date, _ := time.Parse("2006-01-02", key)
+		lines = append(lines, fmt.Sprintf("%s: %s", date.Format("Mon 2 Jan"), formatTime(s.Dates[key])))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// UnmarshalJSON reads either a single meal time used every day, or an object with "default",
+// "weekdays" (keyed by weekday name) and "dates" (keyed by YYYY-MM-DD) times, where null means not served.
+func (s *schedule) UnmarshalJSON(b []byte) error {
+	var aux struct {
+		Start    *timeofday.Time
+		End      *timeofday.Time
+		Default  *mealTime
+		Weekdays map[string]*mealTime
+		Dates    map[string]*mealTime
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	if aux.Start != nil || aux.End != nil {
+		if aux.Start == nil || aux.End == nil {
+			return fmt.Errorf("schedule: meal time needs a start and an end")
+		}
+		*s = everyDay(mealTime{*aux.Start, *aux.End})
+		return nil
+	}
+
+	*s = schedule{Default: aux.Default}
+	if len(aux.Weekdays) != 0 {
+		s.Weekdays = make(map[time.Weekday]*mealTime, len(aux.Weekdays))
+	}
+	for name, mt := range aux.Weekdays {
+		weekday, ok := parseWeekday(name)
+		if !ok {
+			return fmt.Errorf("schedule: unknown weekday %q", name)
+		}
+		s.Weekdays[weekday] = mt
+	}
+
+	if len(aux.Dates) != 0 {
+		s.Dates = make(map[string]*mealTime, len(aux.Dates))
+	}
+	for key, mt := range aux.Dates {
+		date, err := time.Parse("2006-01-02", key)
+		if err != nil {
+			return fmt.Errorf("schedule: invalid date %q (expected YYYY-MM-DD)", key)
+		}
+		s.Dates[menus.DateKey(date)] = mt
+	}
+
+	return nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.ToLower(d.String()) == name {
+			return d, true
+		}
+	}
+
+	return 0, false
+}
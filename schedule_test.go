@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ratorx/chumenu-go/timeofday"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	brunchTime   = mealTime{timeofday.New(11, 0), timeofday.New(13, 0)}
+	examDinner   = mealTime{timeofday.New(18, 30), timeofday.New(20, 0)}
+	testSchedule = schedule{
+		Default: &lunchTime,
+		Weekdays: map[time.Weekday]*mealTime{
+			time.Saturday: &brunchTime,
+			time.Sunday:   nil,
+		},
+		Dates: map[string]*mealTime{
+			"2019-06-05": nil,         // closed on a Wednesday
+			"2019-06-09": &examDinner, // open on a Sunday
+			"2019-06-15": &lunchTime,  // usual time on a Saturday
+		},
+	}
+)
+
+// june returns midday on the day of June 2019, the 3rd is a Monday
+func june(day int) time.Time {
+	return time.Date(2019, time.June, day, 12, 0, 0, 0, time.UTC)
+}
+
+func TestSchedule_On(t *testing.T) {
+	for _, c := range []struct {
+		date     time.Time
+		expected *mealTime
+	}{
+		{june(3), &lunchTime},
+		// Weekday overrides take precedence over the default
+		{june(8), &brunchTime},
+		{june(2), nil},
+		// Date overrides take precedence over weekday overrides, and nil means closed
+		{june(5), nil},
+		{june(9), &examDinner},
+		{june(15), &lunchTime},
+	} {
+		mt, ok := testSchedule.on(c.date)
+		if c.expected == nil {
+			assert.False(t, ok, "%v served", c.date)
+			continue
+		}
+		assert.True(t, ok, "%v not served", c.date)
+		assert.Equal(t, *c.expected, mt, "%v", c.date)
+	}
+
+	_, ok := schedule{}.on(june(3))
+	assert.False(t, ok, "Empty schedule served")
+}
+
+func TestSchedule_Next(t *testing.T) {
+	for _, c := range []struct {
+		from     time.Time
+		date     time.Time
+		expected mealTime
+	}{
+		{june(3), june(3), lunchTime},
+		{june(5), june(6), lunchTime},
+		{june(9), june(9), examDinner},
+		{june(16), june(17), lunchTime},
+	} {
+		date, mt, ok := testSchedule.next(c.from)
+		require.True(t, ok, "%v", c.from)
+		assert.Equal(t, c.date, date, "%v", c.from)
+		assert.Equal(t, c.expected, mt, "%v", c.from)
+	}
+
+	// Only dates within the lookahead are searched
+	once := schedule{Dates: map[string]*mealTime{"2019-06-30": &lunchTime}}
+	_, _, ok := once.next(june(3))
+	assert.False(t, ok, "Found a meal beyond the lookahead")
+	date, _, ok := once.next(june(20))
+	assert.True(t, ok)
+	assert.Equal(t, june(30), date)
+}
+
+func TestSchedule_Describe(t *testing.T) {
+	assert.Equal(t, "12:15 - 13:45\n"+
+		"Saturday: 11:00 - 13:00\n"+
+		"Sunday: Not served\n"+
+		"Sun 9 Jun: 18:30 - 20:00\n"+
+		"Sat 15 Jun: 12:15 - 13:45", testSchedule.describe(june(6)), "Past dates not left out")
+
+	assert.Equal(t, "Not usually served\nWed 5 Jun: 12:15 - 13:45", schedule{Dates: map[string]*mealTime{"2019-06-05": &lunchTime}}.describe(june(3)))
+}
+
+func TestSchedule_UnmarshalJSON(t *testing.T) {
+	for _, c := range []struct {
+		json     string
+		expected schedule
+	}{
+		{`{"start": "12:15", "end": "13:45"}`, everyDay(lunchTime)},
+		{`{"default": {"start": "12:15", "end": "13:45"}}`, everyDay(lunchTime)},
+		{`{"default": {"start": "12:15", "end": "13:45"}, "weekdays": {"Saturday": {"start": "11:00", "end": "13:00"}, "sunday": null}, "dates": {"2019-06-05": null, "2019-06-09": {"start": "18:30", "end": "20:00"}, "2019-06-15": {"start": "12:15", "end": "13:45"}}}`, testSchedule},
+		{`{"weekdays": {"monday": {"start": "12:15", "end": "13:45"}}}`, schedule{Weekdays: map[time.Weekday]*mealTime{time.Monday: &lunchTime}}},
+	} {
+		var s schedule
+		require.NoError(t, json.Unmarshal([]byte(c.json), &s), c.json)
+		assert.Equal(t, c.expected, s, c.json)
+	}
+
+	for _, text := range []string{
+		`{"start": "12:15"}`,
+		`{"start": "12:15", "end": "lunchtime"}`,
+		`{"default": {"end": "13:45"}}`,
+		`{"weekdays": {"someday": {"start": "12:15", "end": "13:45"}}}`,
+		`{"dates": {"5/6/19": {"start": "12:15", "end": "13:45"}}}`,
+		`[]`,
+	} {
+		var s schedule
+		assert.Error(t, json.Unmarshal([]byte(text), &s), text)
+	}
+}
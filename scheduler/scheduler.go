@@ -0,0 +1,218 @@
+// Package scheduler runs recurring jobs, recording when each job last ran in BoltDB so that runs missed
+// while the process was stopped can be caught up when it starts again.
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Clock returns the current time. time.Now is used in production, and tests can substitute a fake clock.
+type Clock func() time.Time
+
+// Schedule decides when a job runs
+type Schedule interface {
+	// Next returns the first run strictly after t
+	Next(t time.Time) time.Time
+}
+
+// Every is a Schedule which runs at multiples of a duration, e.g. on the minute or on the hour
+type Every time.Duration
+
+// Next returns the first multiple of e after t
+func (e Every) Next(t time.Time) time.Time {
+	d := time.Duration(e)
+	return t.Truncate(d).Add(d)
+}
+
+// Run is a planned run of a job
+type Run struct {
+	Job string
+	At  time.Time
+}
+
+type job struct {
+	name     string
+	schedule Schedule
+	run      func(at time.Time)
+	next     time.Time
+}
+
+// Scheduler runs jobs when they are due
+type Scheduler struct {
+	db     *bolt.DB
+	bucket []byte
+	clock  Clock
+	grace  time.Duration // how late a missed run can be caught up
+
+	mu   sync.Mutex
+	jobs []*job
+	wake chan struct{}
+}
+
+// New returns a Scheduler which records the last run of each job in bucket.
+// Runs missed by up to grace, e.g. while the process was restarting, are run late rather than skipped.
+func New(db *bolt.DB, bucket string, clock Clock, grace time.Duration) (*Scheduler, error) {
+	s := &Scheduler{db: db, bucket: []byte(bucket), clock: clock, grace: grace, wake: make(chan struct{}, 1)}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(s.bucket)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Add schedules run under a unique name, which identifies the job's last run across restarts.
+// run is passed the time the run was planned for, which is in the past when a missed run is caught up.
+func (s *Scheduler) Add(name string, schedule Schedule, run func(at time.Time)) error {
+	last, err := s.LastRun(name)
+	if err != nil {
+		return err
+	}
+
+	now := s.clock()
+	j := &job{name: name, schedule: schedule, run: run}
+
+	switch {
+	case last.IsZero():
+		// New jobs have nothing to catch up
+		j.next = schedule.Next(now)
+	case schedule.Next(last).Before(now.Add(-s.grace)):
+		// Runs missed by more than the grace period are skipped
+		j.next = schedule.Next(now.Add(-s.grace))
+	default:
+		j.next = schedule.Next(last)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.jobs {
+		if existing.name == name {
+			return fmt.Errorf("scheduler: job %v already added", name)
+		}
+	}
+	s.jobs = append(s.jobs, j)
+
+	// The new job may be due before the loop would next wake
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// LastRun returns the planned time of the last run of the named job, or the zero time if it has never run
+func (s *Scheduler) LastRun(name string) (time.Time, error) {
+	var last time.Time
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %s not found", s.bucket)
+		}
+
+		if v := b.Get([]byte(name)); v != nil {
+			return last.UnmarshalText(v)
+		}
+		return nil
+	})
+
+	return last, err
+}
+
+func (s *Scheduler) recordRun(name string, at time.Time) error {
+	v, err := at.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %s not found", s.bucket)
+		}
+
+		return b.Put([]byte(name), v)
+	})
+}
+
+// RunDue runs every job which is due, in the order they were planned, and returns the number of runs.
+// Jobs which missed several runs are run once for each, so they can catch up.
+func (s *Scheduler) RunDue() (int, error) {
+	num := 0
+	for {
+		now := s.clock()
+
+		s.mu.Lock()
+		var due *job
+		for _, j := range s.jobs {
+			if !j.next.After(now) && (due == nil || j.next.Before(due.next)) {
+				due = j
+			}
+		}
+
+		if due == nil {
+			s.mu.Unlock()
+			return num, nil
+		}
+
+		at := due.next
+		due.next = due.schedule.Next(at)
+		s.mu.Unlock()
+
+		// Recorded first, so a run which crashes the process is not repeated forever.
+		// A run which could not be recorded stays due, so it is retried.
+		if err := s.recordRun(due.name, at); err != nil {
+			s.mu.Lock()
+			due.next = at
+			s.mu.Unlock()
+			return num, err
+		}
+		due.run(at)
+		num++
+	}
+}
+
+// Planned returns the next run of every job, in time order
+func (s *Scheduler) Planned() []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := make([]Run, len(s.jobs))
+	for i, j := range s.jobs {
+		runs[i] = Run{Job: j.name, At: j.next}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].At.Before(runs[j].At) })
+
+	return runs
+}
+
+// Start runs jobs as they become due until stop is closed.
+// Errors recording runs are passed to onError, and the job is retried after a minute.
+func (s *Scheduler) Start(stop <-chan struct{}, onError func(error)) {
+	for {
+		wait := time.Minute
+		if _, err := s.RunDue(); err != nil {
+			onError(err)
+		} else if planned := s.Planned(); len(planned) != 0 {
+			wait = planned[0].At.Sub(s.clock())
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-s.wake:
+		case <-time.After(wait):
+		}
+	}
+}
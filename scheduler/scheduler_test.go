@@ -0,0 +1,185 @@
+package scheduler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDB(t *testing.T) (*bolt.DB, func()) {
+	dir, err := ioutil.TempDir("", "scheduler")
+	require.NoError(t, err)
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// fakeClock is a Clock which only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// recorder collects the planned times of a job's runs
+type recorder []time.Time
+
+func (r *recorder) run(at time.Time) {
+	*r = append(*r, at)
+}
+
+func at(hour, minute int) time.Time {
+	return time.Date(2019, time.June, 3, hour, minute, 0, 0, time.UTC)
+}
+
+func TestEvery_Next(t *testing.T) {
+	for _, c := range []struct {
+		every    Every
+		t        time.Time
+		expected time.Time
+	}{
+		{Every(time.Minute), at(11, 29), at(11, 30)},
+		{Every(time.Minute), at(11, 29).Add(30 * time.Second), at(11, 30)},
+		{Every(time.Hour), at(11, 29), at(12, 0)},
+		{Every(time.Hour), at(12, 0), at(13, 0)},
+	} {
+		assert.Equal(t, c.expected, c.every.Next(c.t), "%v after %v", time.Duration(c.every), c.t)
+	}
+}
+
+func TestScheduler_RunDue(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	clock := &fakeClock{at(11, 29)}
+	s, err := New(db, "jobs", clock.Now, 10*time.Minute)
+	require.NoError(t, err)
+
+	var runs recorder
+	require.NoError(t, s.Add("messages", Every(time.Minute), runs.run))
+
+	num, err := s.RunDue()
+	require.NoError(t, err)
+	assert.Equal(t, 0, num, "New job run before it was due")
+
+	clock.now = at(11, 31)
+	num, err = s.RunDue()
+	require.NoError(t, err)
+	assert.Equal(t, 2, num)
+	assert.Equal(t, recorder{at(11, 30), at(11, 31)}, runs)
+
+	last, err := s.LastRun("messages")
+	require.NoError(t, err)
+	assert.True(t, at(11, 31).Equal(last), "Last run not recorded: %v", last)
+}
+
+func TestScheduler_CatchUp(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	clock := &fakeClock{at(11, 28)}
+	s, err := New(db, "jobs", clock.Now, 10*time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Add("messages", Every(time.Minute), func(time.Time) {}))
+	clock.now = at(11, 29)
+	_, err = s.RunDue()
+	require.NoError(t, err)
+
+	// Restarted four minutes later, so the 11:30 run is still caught up
+	clock.now = at(11, 33)
+	s, err = New(db, "jobs", clock.Now, 10*time.Minute)
+	require.NoError(t, err)
+
+	var runs recorder
+	require.NoError(t, s.Add("messages", Every(time.Minute), runs.run))
+	assert.Equal(t, []Run{{"messages", at(11, 30)}}, s.Planned())
+
+	_, err = s.RunDue()
+	require.NoError(t, err)
+	assert.Equal(t, recorder{at(11, 30), at(11, 31), at(11, 32), at(11, 33)}, runs)
+}
+
+func TestScheduler_SkipsBeyondGrace(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	clock := &fakeClock{at(9, 0)}
+	s, err := New(db, "jobs", clock.Now, 10*time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Add("messages", Every(time.Minute), func(time.Time) {}))
+	clock.now = at(9, 1)
+	_, err = s.RunDue()
+	require.NoError(t, err)
+
+	// Restarted hours later, only the runs in the grace period are caught up
+	clock.now = at(11, 33)
+	s, err = New(db, "jobs", clock.Now, 10*time.Minute)
+	require.NoError(t, err)
+
+	var runs recorder
+	require.NoError(t, s.Add("messages", Every(time.Minute), runs.run))
+	_, err = s.RunDue()
+	require.NoError(t, err)
+	require.Len(t, runs, 10)
+	assert.Equal(t, at(11, 24), runs[0])
+	assert.Equal(t, at(11, 33), runs[len(runs)-1])
+}
+
+func TestScheduler_Planned(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	clock := &fakeClock{at(11, 29)}
+	s, err := New(db, "jobs", clock.Now, 10*time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Add("refresh", Every(time.Hour), func(time.Time) {}))
+	require.NoError(t, s.Add("messages", Every(time.Minute), func(time.Time) {}))
+	assert.Error(t, s.Add("messages", Every(time.Minute), func(time.Time) {}), "Duplicate job added")
+
+	assert.Equal(t, []Run{{"messages", at(11, 30)}, {"refresh", at(12, 0)}}, s.Planned())
+
+	clock.now = at(11, 30)
+	_, err = s.RunDue()
+	require.NoError(t, err)
+	assert.Equal(t, []Run{{"messages", at(11, 31)}, {"refresh", at(12, 0)}}, s.Planned())
+}
+
+func TestScheduler_RetriesUnrecordedRun(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	clock := &fakeClock{at(11, 29)}
+	s, err := New(db, "jobs", clock.Now, 10*time.Minute)
+	require.NoError(t, err)
+
+	var runs recorder
+	require.NoError(t, s.Add("messages", Every(time.Minute), runs.run))
+
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error { return tx.DeleteBucket([]byte("jobs")) }))
+	clock.now = at(11, 30)
+	_, err = s.RunDue()
+	assert.Error(t, err, "Failure to record the run not reported")
+	assert.Empty(t, runs, "Run without being recorded")
+	assert.Equal(t, []Run{{"messages", at(11, 30)}}, s.Planned(), "Unrecorded run lost")
+
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("jobs"))
+		return err
+	}))
+	_, err = s.RunDue()
+	require.NoError(t, err)
+	assert.Equal(t, recorder{at(11, 30)}, runs)
+}
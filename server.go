@@ -9,31 +9,51 @@ import (
 	"time"
 
 	"strconv"
+	_ "time/tzdata" // zone data for containers without it
 
 	"github.com/boltdb/bolt"
-	"github.com/jasonlvhit/gocron"
 	"github.com/ratorx/chumenu-go/facebook"
+	"github.com/ratorx/chumenu-go/menus"
+	"github.com/ratorx/chumenu-go/scheduler"
+	"github.com/ratorx/chumenu-go/timeofday"
 )
 
 const (
 	defaultUserBucket = "users"
+	defaultMenuBucket = "menus"
+	archiveBucket     = "archive"
+	jobBucket         = "jobs"
 	forceTimedMessage = false
+	refreshInterval   = time.Hour // time between menu refreshes
+	maxMenuAge        = 24 * time.Hour
+
+	// Scraper health alerts
+	healthThreshold    = 3 // consecutive failed refreshes
+	healthMaxUnchanged = 8 * 24 * time.Hour
+	healthCooldown     = 12 * time.Hour
+
+	defaultLocation = "Europe/London"
 )
 
 var (
-	lunchTime        = mealTime{hourMinute{12, 15}, hourMinute{13, 45}}
-	dinnerTime       = mealTime{hourMinute{17, 45}, hourMinute{19, 15}}
-	interval   uint8 = 45
+	lunchTime  = mealTime{timeofday.New(12, 15), timeofday.New(13, 45)}
+	dinnerTime = mealTime{timeofday.New(17, 45), timeofday.New(19, 15)}
+	interval   = 45 * time.Minute // notice given before a meal starts
 )
 
 type config struct {
 	admin      string               // admin user
 	certPath   string               // path to cert.pem
+	clock      func() time.Time     // current time, replaced in tests
 	sendClient *facebook.SendClient // api client for sending messages
 	webhook    *facebook.Webhook    // Facebook Webhook handler
 	db         *bolt.DB             // db reference
 	keyPath    string               // path to privkey.pem
 	port       uint                 // server port
+	scheduler  *scheduler.Scheduler // runs the menu refreshes and timed messages
+	venues     []*venue             // dining halls, the first is the default
+	calendar   calendar             // term dates and closures from the calendar file
+	location   *time.Location       // time zone of the venues, used for all dates and times
 	userBucket string               // bucket for users
 	debug      *log.Logger          // Logger for all packages
 }
@@ -63,7 +83,12 @@ func getPort(env string, def uint) uint {
 	return uint(port)
 }
 
-func init() {
+// now returns the current time in the venues' time zone
+func now() time.Time {
+	return cfg.clock().In(cfg.location)
+}
+
+func setup() {
 	cfg.certPath = getConfigValue("SSL_CERT_PATH", "")
 	cfg.keyPath = getConfigValue("SSL_KEY_PATH", "")
 	cfg.userBucket = getConfigValue("USER_BUCKET", defaultUserBucket)
@@ -76,6 +101,15 @@ func init() {
 	// Debug Logger
 	cfg.debug = log.New(os.Stdout, "", log.Lshortfile)
 
+	// Time zone
+	// Meal times are wall clock times at the venue, whatever the zone of the server
+	location, err := time.LoadLocation(getConfigValue("TIMEZONE", defaultLocation))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	cfg.location = location
+	cfg.clock = time.Now
+
 	// Facebook Send Client
 	cfg.sendClient = &facebook.SendClient{AccessToken: accessToken, BaseURL: facebook.APIBase, Metadata: "Churchill Menus"}
 
@@ -93,18 +127,85 @@ func init() {
 	cfg.db = db
 
 	err = cfg.db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(cfg.userBucket)) // nolint: vetshadow
-		return err
+		for _, bucket := range []string{cfg.userBucket, watchBucket, preferenceBucket, calendarBucket, announcementBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil { // nolint: vetshadow
+				return err
+			}
+		}
+		return migrateSubscribers(tx)
 	})
 
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	// Lunch
-	gocron.Every(1).Day().At(lunchTime.Start.Before(interval).String()).Do(timedMessage, true, forceTimedMessage)
-	// Dinner
-	gocron.Every(1).Day().At(dinnerTime.Start.Before(interval).String()).Do(timedMessage, false, forceTimedMessage)
+	// Term dates and closures
+	if calendarFile := getConfigValue("CALENDAR_FILE", ""); calendarFile != "" {
+		cfg.calendar, err = loadCalendar(calendarFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	// Scheduler
+	// Runs missed while restarting are caught up, as long as they are no later than the dispatcher would catch up
+	cfg.scheduler, err = scheduler.New(cfg.db, jobBucket, cfg.clock, maxCatchUp)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// Venues
+	// Without a venues file, Churchill is configured from the environment
+	venues := []venueConfig{{
+		Name:    "churchill",
+		Display: "Churchill College",
+		URL:     getConfigValue("MENU_URL", menus.ChurchillURL),
+		File:    getConfigValue("MENU_FILE", ""),
+		Rules:   getConfigValue("RULES_FILE", ""),
+		Times:   map[string]schedule{menus.Lunch: everyDay(lunchTime), menus.Dinner: everyDay(dinnerTime)},
+	}}
+	if venuesFile := getConfigValue("VENUES_FILE", ""); venuesFile != "" {
+		venues, err = loadVenueConfigs(venuesFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	for i, c := range venues {
+		// The default venue keeps the original buckets
+		suffix := ""
+		if i != 0 {
+			suffix = "-" + c.Name
+		}
+
+		v, err := newVenue(c, cfg.db, suffix) // nolint: vetshadow
+		if err != nil {
+			log.Fatalln(err)
+		}
+		cfg.venues = append(cfg.venues, v)
+
+		// Menu refresh
+		err = cfg.scheduler.Add("refresh/"+v.name, scheduler.Every(refreshInterval), func(time.Time) { go refreshMenus(v) })
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	// Timed messages
+	// Checked every minute rather than scheduled as daily jobs, which drift by an hour when the clocks change
+	d := newDispatcher(cfg.venues, subscriberPlan, func(v *venue, meal string, lead time.Duration) {
+		go timedMessage(v, meal, lead, forceTimedMessage)
+	}, func(v *venue, mode string, at timeofday.Time) {
+		go digestMessage(v, mode, at)
+	})
+	if err = cfg.scheduler.Add("messages", scheduler.Every(time.Minute), d.tick); err != nil {
+		log.Fatalln(err)
+	}
+
+	// Scheduled announcements
+	if err = cfg.scheduler.Add("announcements", scheduler.Every(time.Minute), sendAnnouncements); err != nil {
+		log.Fatalln(err)
+	}
 
 	// api handler
 	http.HandleFunc("/webhook", cfg.webhook.ResponseHandler)
@@ -113,10 +214,15 @@ func init() {
 }
 
 func main() {
+	setup()
 	log.SetFlags(0)
 	defer cfg.db.Close() // nolint: errcheck
+	// fetch the menus before the first scheduled refresh
+	for _, v := range cfg.venues {
+		go refreshMenus(v)
+	}
 	// start timed messages
-	go func() { <-gocron.Start() }()
+	go cfg.scheduler.Start(nil, func(err error) { cfg.debug.Print(err) })
 
 	// start webserver in default thread
 	if cfg.certPath == "" || cfg.keyPath == "" {
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ratorx/chumenu-go/timeofday"
+)
+
+const (
+	maxLead = 180 // longest notice before a meal, in minutes
+
+	// Subscriber messages
+	subscribeMealSuccess   = "Subscribed to %s messages."
+	subscribeMealFail      = "Already subscribed to %s messages."
+	subscribeMealNotServed = "%s is not served at %s."
+	unsubscribeMealSuccess = "Unsubscribed from %s messages."
+	unsubscribeMealFail    = "Not subscribed to %s messages."
+	notifySuccess          = "Menus will be sent %v minutes before each meal."
+	notifyInvalid          = "Give the number of minutes notice from 1 to %v, e.g. *notify 20*."
+	notifySubscribe        = "Subscribe first to choose when menus are sent."
+)
+
+// subscriber is the record stored against each subscriber in the user bucket
+type subscriber struct {
+	Meals    []string        `json:",omitempty"` // meals to receive, every meal if empty
+	Lead     int             `json:",omitempty"` // minutes notice before a meal, interval if zero
+	Digest   string          `json:",omitempty"` // digest mode, which replaces the messages before each meal
+	DigestAt *timeofday.Time `json:",omitempty"` // time of the daily digest
+}
+
+// Returns the subscriber stored as v. Subscribers stored before records were added have an empty value.
+func decodeSubscriber(v []byte) (subscriber, error) {
+	var s subscriber
+	if len(v) == 0 {
+		return s, nil
+	}
+
+	err := json.Unmarshal(v, &s)
+	return s, err
+}
+
+func putSubscriber(b *bolt.Bucket, sender []byte, s subscriber) error {
+	v, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(sender, v)
+}
+
+// wants reports whether the subscriber receives messages for the named meal
+func (s subscriber) wants(meal string) bool {
+	if len(s.Meals) == 0 {
+		return true
+	}
+
+	for _, m := range s.Meals {
+		if m == meal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lead returns the notice the subscriber gets before a meal
+func (s subscriber) lead() time.Duration {
+	if s.Lead == 0 {
+		return interval
+	}
+
+	return time.Duration(s.Lead) * time.Minute
+}
+
+// digestAt returns the time of the subscriber's daily digest
+func (s subscriber) digestAt() timeofday.Time {
+	if s.DigestAt == nil {
+		return defaultDigestTime
+	}
+
+	return *s.DigestAt
+}
+
+// migrateSubscribers replaces the empty values of subscribers stored before records were added
+func migrateSubscribers(tx *bolt.Tx) error {
+	b := tx.Bucket([]byte(cfg.userBucket))
+	if b == nil {
+		return fmt.Errorf("database corrupted: bucket %v not found", cfg.userBucket)
+	}
+
+	var legacy [][]byte
+	b.ForEach(func(k, v []byte) error { // nolint: errcheck
+		if len(v) == 0 {
+			legacy = append(legacy, append([]byte{}, k...))
+		}
+		return nil
+	})
+
+	for _, k := range legacy {
+		if err := putSubscriber(b, k, subscriber{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// plan contains the distinct times chosen by subscribers, which the dispatcher checks for due messages
+type plan struct {
+	leads  map[time.Duration]bool  // notice before meals
+	digest map[timeofday.Time]bool // times of daily digests
+}
+
+// subscriberPlan returns the times at which subscribers receive messages
+func subscriberPlan() plan {
+	p := plan{leads: make(map[time.Duration]bool), digest: make(map[timeofday.Time]bool)}
+
+	err := cfg.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(cfg.userBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", cfg.userBucket)
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			// A corrupt record only affects its own subscriber
+			s, err := decodeSubscriber(v)
+			if err != nil {
+				cfg.debug.Print(err)
+				return nil
+			}
+			switch s.Digest {
+			case noDigest:
+				p.leads[s.lead()] = true
+			case dailyDigest:
+				p.digest[s.digestAt()] = true
+			}
+			return nil
+		})
+	})
+
+	if err != nil {
+		cfg.debug.Print(err)
+	}
+
+	return p
+}
+
+// notifyHandler sets how many minutes before each meal the subscriber is sent the menu
+func notifyHandler(sender, text string) {
+	minutes, err := strconv.Atoi(text)
+	if err != nil || minutes < 1 || minutes > maxLead {
+		responseMessage(sender, fmt.Sprintf(notifyInvalid, maxLead), standardQR)
+		return
+	}
+
+	s := []byte(sender)
+	err = cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(cfg.userBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", cfg.userBucket)
+		}
+
+		v := b.Get(s)
+		if v == nil {
+			go responseMessage(sender, notifySubscribe, unsubscriptionQR)
+			return nil
+		}
+
+		sub, err := decodeSubscriber(v) // nolint: vetshadow
+		if err != nil {
+			return err
+		}
+
+		sub.Lead = minutes
+		if err = putSubscriber(b, s, sub); err != nil {
+			go responseMessage(sender, unexpected, standardQR)
+			return err
+		}
+		go responseMessage(sender, fmt.Sprintf(notifySuccess, minutes), subscriptionQR)
+
+		return nil
+	})
+
+	if err != nil {
+		cfg.debug.Print(err)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ratorx/chumenu-go/menus"
+	"github.com/ratorx/chumenu-go/timeofday"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storedSubscriber returns the record stored for sender, and false if they are not subscribed
+func storedSubscriber(t *testing.T, sender string) (subscriber, bool) {
+	var (
+		s     subscriber
+		found bool
+	)
+
+	require.NoError(t, cfg.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(cfg.userBucket)).Get([]byte(sender))
+		if v == nil {
+			return nil
+		}
+
+		found = true
+		var err error
+		s, err = decodeSubscriber(v)
+		return err
+	}))
+
+	return s, found
+}
+
+func TestSubscriber_Wants(t *testing.T) {
+	for _, c := range []struct {
+		meals    []string
+		meal     string
+		expected bool
+	}{
+		{nil, menus.Lunch, true},
+		{nil, menus.Breakfast, true},
+		{[]string{menus.Lunch}, menus.Lunch, true},
+		{[]string{menus.Lunch}, menus.Dinner, false},
+		{[]string{menus.Dinner, menus.Formal}, menus.Formal, true},
+	} {
+		assert.Equal(t, c.expected, subscriber{Meals: c.meals}.wants(c.meal), "%v wants %v", c.meals, c.meal)
+	}
+}
+
+func TestSubscriber_Defaults(t *testing.T) {
+	assert.Equal(t, interval, subscriber{}.lead())
+	assert.Equal(t, 20*time.Minute, subscriber{Lead: 20}.lead())
+
+	assert.Equal(t, defaultDigestTime, subscriber{}.digestAt())
+	at := timeofday.New(7, 30)
+	assert.Equal(t, at, subscriber{DigestAt: &at}.digestAt())
+}
+
+func TestDecodeSubscriber(t *testing.T) {
+	// Subscribers stored before records were added have an empty value
+	for _, v := range [][]byte{nil, {}} {
+		s, err := decodeSubscriber(v)
+		assert.NoError(t, err)
+		assert.Equal(t, subscriber{}, s)
+	}
+
+	s, err := decodeSubscriber([]byte(`{"Meals":["Lunch"],"Lead":20,"Digest":"daily","DigestAt":"07:30"}`))
+	require.NoError(t, err)
+	at := timeofday.New(7, 30)
+	assert.Equal(t, subscriber{Meals: []string{menus.Lunch}, Lead: 20, Digest: dailyDigest, DigestAt: &at}, s)
+
+	_, err = decodeSubscriber([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestMigrateSubscribers(t *testing.T) {
+	_, cleanup := testConfig(t)
+	defer cleanup()
+
+	require.NoError(t, cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(cfg.userBucket))
+		for i := 0; i < 3; i++ {
+			if err := b.Put([]byte(fmt.Sprint("legacy", i)), []byte{}); err != nil {
+				return err
+			}
+		}
+		return putSubscriber(b, []byte(testUser), subscriber{Meals: []string{menus.Lunch}})
+	}))
+
+	require.NoError(t, cfg.db.Update(migrateSubscribers))
+
+	require.NoError(t, cfg.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(cfg.userBucket)).ForEach(func(k, v []byte) error {
+			assert.NotEmpty(t, v, "%s not migrated", k)
+			return nil
+		})
+	}))
+
+	s, ok := storedSubscriber(t, "legacy0")
+	assert.True(t, ok)
+	assert.Equal(t, subscriber{}, s)
+	s, _ = storedSubscriber(t, testUser)
+	assert.Equal(t, []string{menus.Lunch}, s.Meals, "Existing record changed")
+}
+
+func TestSubscribeHandler_Meals(t *testing.T) {
+	_, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+
+	// The test venue only serves lunch and dinner
+	subscribeHandler(testUser, menus.Breakfast)
+	assert.Equal(t, "Breakfast is not served at Test.", out.last(testUser, 1))
+	_, ok := storedSubscriber(t, testUser)
+	assert.False(t, ok, "Subscribed to a meal which is not served")
+
+	subscribeHandler(testUser, menus.Lunch)
+	assert.Equal(t, "Subscribed to lunch messages.", out.last(testUser, 2))
+	subscribeHandler(testUser, menus.Lunch)
+	assert.Equal(t, "Already subscribed to lunch messages.", out.last(testUser, 3))
+	subscribeHandler(testUser, menus.Dinner)
+	assert.Equal(t, "Subscribed to dinner messages.", out.last(testUser, 4))
+
+	s, ok := storedSubscriber(t, testUser)
+	require.True(t, ok)
+	assert.Equal(t, []string{menus.Lunch, menus.Dinner}, s.Meals)
+}
+
+func TestUnsubscribeHandler_Meals(t *testing.T) {
+	_, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+
+	subscribeHandler(testUser, "")
+	assert.Equal(t, subscribeSuccess, out.last(testUser, 1))
+
+	// Every meal is expanded to the meals served, less the one removed
+	unsubscribeHandler(testUser, menus.Dinner)
+	assert.Equal(t, "Unsubscribed from dinner messages.", out.last(testUser, 2))
+	s, ok := storedSubscriber(t, testUser)
+	require.True(t, ok)
+	assert.Equal(t, []string{menus.Lunch}, s.Meals)
+
+	unsubscribeHandler(testUser, menus.Dinner)
+	assert.Equal(t, "Not subscribed to dinner messages.", out.last(testUser, 3))
+
+	// Removing the last meal unsubscribes
+	unsubscribeHandler(testUser, menus.Lunch)
+	assert.Equal(t, unsubscribeSuccess, out.last(testUser, 4))
+	_, ok = storedSubscriber(t, testUser)
+	assert.False(t, ok, "Still subscribed without any meals")
+}
+
+func TestCorruptSubscriber_Skipped(t *testing.T) {
+	_, cleanup := testConfig(t)
+	defer cleanup()
+	out, stop := testOutbox(t)
+	defer stop()
+
+	// "corrupt" is read before testUser
+	require.NoError(t, cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(cfg.userBucket))
+		if err := b.Put([]byte("corrupt"), []byte("{")); err != nil {
+			return err
+		}
+		return putSubscriber(b, []byte(testUser), subscriber{Lead: 45})
+	}))
+
+	p := subscriberPlan()
+	assert.Equal(t, map[time.Duration]bool{45 * time.Minute: true}, p.leads, "Subscribers after a corrupt record lost")
+
+	num, err := broadcast("Hello")
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), num, "Corrupt record sent a message")
+	assert.Equal(t, []string{"Hello"}, out.to(testUser, 1))
+}
@@ -0,0 +1,110 @@
+// Package timeofday provides a wall clock time of day, to the minute, independent of any date or time zone
+package timeofday
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const minutesPerDay = 24 * 60
+
+// Time is a time of day between 00:00 and 23:59.
+// Times can be compared with ==, and the zero value is midnight.
+type Time struct {
+	minutes int // since midnight
+}
+
+// New returns the time hour:minute. Values outside a day wrap around, so New(24, 30) is 00:30.
+func New(hour, minute int) Time {
+	return Time{mod(hour*60+minute, minutesPerDay)}
+}
+
+// Of returns the wall clock time of t in its location
+func Of(t time.Time) Time {
+	return New(t.Hour(), t.Minute())
+}
+
+// Parse reads a time in the form "15:04" or "9:30"
+func Parse(s string) (Time, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 || len(parts[1]) != 2 {
+		return Time{}, fmt.Errorf("timeofday: invalid time %q (expected HH:MM)", s)
+	}
+
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return Time{}, fmt.Errorf("timeofday: invalid hour in %q", s)
+	}
+
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return Time{}, fmt.Errorf("timeofday: invalid minute in %q", s)
+	}
+
+	return New(hour, minute), nil
+}
+
+// Hour returns the hour, between 0 and 23
+func (t Time) Hour() int {
+	return t.minutes / 60
+}
+
+// Minute returns the minute within the hour, between 0 and 59
+func (t Time) Minute() int {
+	return t.minutes % 60
+}
+
+// Add returns the time d after t, wrapping around midnight. Durations are truncated to the minute.
+func (t Time) Add(d time.Duration) Time {
+	return Time{mod(t.minutes+int(d/time.Minute)%minutesPerDay, minutesPerDay)}
+}
+
+// Sub returns the duration from u to t on the same day, which is negative if t is before u
+func (t Time) Sub(u Time) time.Duration {
+	return time.Duration(t.minutes-u.minutes) * time.Minute
+}
+
+// Before reports whether t is earlier in the day than u
+func (t Time) Before(u Time) bool {
+	return t.minutes < u.minutes
+}
+
+// After reports whether t is later in the day than u
+func (t Time) After(u Time) bool {
+	return t.minutes > u.minutes
+}
+
+// On returns the instant at which t occurs in loc on the calendar date of date (in date's own location).
+// Times skipped or repeated when the clocks change are resolved by time.Date, e.g. in Europe/London 01:30
+// becomes 02:30 BST when the clocks go forward, and is the second (GMT) occurrence when they go back.
+func (t Time) On(date time.Time, loc *time.Location) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, loc)
+}
+
+func (t Time) String() string {
+	return fmt.Sprintf("%02d:%02d", t.Hour(), t.Minute())
+}
+
+// MarshalText writes the time in the form "15:04"
+func (t Time) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText reads a time in the form accepted by Parse
+func (t *Time) UnmarshalText(b []byte) error {
+	parsed, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+
+	*t = parsed
+	return nil
+}
+
+// Returns a modulo b, which is never negative
+func mod(a, b int) int {
+	return ((a % b) + b) % b
+}
@@ -0,0 +1,132 @@
+package timeofday
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	for _, c := range []struct {
+		hour, minute int
+		expected     string
+	}{
+		{0, 0, "00:00"},
+		{12, 15, "12:15"},
+		{23, 59, "23:59"},
+		{24, 30, "00:30"},
+		{0, -15, "23:45"},
+		{1, 90, "02:30"},
+	} {
+		assert.Equal(t, c.expected, New(c.hour, c.minute).String(), "New(%v, %v)", c.hour, c.minute)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		text     string
+		expected Time
+	}{
+		{"12:15", New(12, 15)},
+		{"9:30", New(9, 30)},
+		{" 00:00 ", New(0, 0)},
+		{"23:59", New(23, 59)},
+	} {
+		actual, err := Parse(c.text)
+		assert.NoError(t, err, c.text)
+		assert.Equal(t, c.expected, actual, c.text)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, text := range []string{"", "12", "12:5", "24:00", "12:60", "-1:00", "noon", "12:15:00"} {
+		_, err := Parse(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestTime_Add(t *testing.T) {
+	for _, c := range []struct {
+		start    Time
+		d        time.Duration
+		expected Time
+	}{
+		{New(12, 15), -45 * time.Minute, New(11, 30)},
+		{New(0, 15), -45 * time.Minute, New(23, 30)},
+		{New(23, 30), time.Hour, New(0, 30)},
+		{New(12, 0), -25 * time.Hour, New(11, 0)},
+		{New(12, 0), 48 * time.Hour, New(12, 0)},
+		{New(12, 0), 90 * time.Second, New(12, 1)},
+	} {
+		assert.Equal(t, c.expected, c.start.Add(c.d), "%v + %v", c.start, c.d)
+	}
+}
+
+func TestTime_Compare(t *testing.T) {
+	for _, c := range []struct {
+		t, u          Time
+		before, after bool
+		sub           time.Duration
+	}{
+		{New(12, 15), New(13, 45), true, false, -90 * time.Minute},
+		{New(13, 45), New(12, 15), false, true, 90 * time.Minute},
+		{New(9, 0), New(9, 0), false, false, 0},
+		{New(0, 0), New(23, 59), true, false, -(23*time.Hour + 59*time.Minute)},
+	} {
+		assert.Equal(t, c.before, c.t.Before(c.u), "%v before %v", c.t, c.u)
+		assert.Equal(t, c.after, c.t.After(c.u), "%v after %v", c.t, c.u)
+		assert.Equal(t, c.sub, c.t.Sub(c.u), "%v - %v", c.t, c.u)
+	}
+}
+
+func TestTime_On(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	for _, c := range []struct {
+		time     Time
+		date     time.Time
+		expected time.Time
+	}{
+		// GMT and BST
+		{New(12, 15), time.Date(2018, time.December, 3, 0, 0, 0, 0, london), time.Date(2018, time.December, 3, 12, 15, 0, 0, time.UTC)},
+		{New(12, 15), time.Date(2019, time.June, 3, 0, 0, 0, 0, london), time.Date(2019, time.June, 3, 11, 15, 0, 0, time.UTC)},
+		// The date is taken in its own location, even late in the evening UTC
+		{New(12, 15), time.Date(2019, time.June, 2, 23, 30, 0, 0, time.UTC), time.Date(2019, time.June, 2, 11, 15, 0, 0, time.UTC)},
+		// Either side of the clocks changing
+		{New(11, 30), time.Date(2019, time.March, 31, 0, 0, 0, 0, london), time.Date(2019, time.March, 31, 10, 30, 0, 0, time.UTC)},
+		{New(0, 30), time.Date(2019, time.March, 31, 0, 0, 0, 0, london), time.Date(2019, time.March, 31, 0, 30, 0, 0, time.UTC)},
+		{New(1, 30), time.Date(2019, time.March, 31, 0, 0, 0, 0, london), time.Date(2019, time.March, 31, 1, 30, 0, 0, time.UTC)},
+		{New(11, 30), time.Date(2018, time.October, 28, 0, 0, 0, 0, london), time.Date(2018, time.October, 28, 11, 30, 0, 0, time.UTC)},
+	} {
+		assert.True(t, c.expected.Equal(c.time.On(c.date, london)), "%v on %v: got %v", c.time, c.date, c.time.On(c.date, london).UTC())
+	}
+}
+
+func TestTime_Of(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	instant := time.Date(2019, time.June, 3, 11, 15, 30, 0, time.UTC)
+	assert.Equal(t, New(11, 15), Of(instant))
+	assert.Equal(t, New(12, 15), Of(instant.In(london)))
+}
+
+func TestTime_JSON(t *testing.T) {
+	var v struct {
+		Start Time
+		End   *Time
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"Start":"12:15","End":"13:45"}`), &v))
+	assert.Equal(t, New(12, 15), v.Start)
+	assert.Equal(t, New(13, 45), *v.End)
+
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Start":"12:15","End":"13:45"}`, string(b))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"Start":"25:00"}`), &v))
+}
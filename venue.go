@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ratorx/chumenu-go/menus"
+)
+
+// Venue messages
+const (
+	venueSuccess = "Venue set to %s."
+	venueList    = "Current venue: %s.\nAvailable venues:\n%s\nChange it with *venue <name>*."
+	venueInvalid = "Venue not recognised. Type *venue* for a list of venues."
+)
+
+// venueConfig is the configuration of a single venue, as stored in the venues file
+type venueConfig struct {
+	Name    string              `json:"name"`    // name used in the venue command
+	Display string              `json:"display"` // name shown to users
+	URL     string              `json:"url"`     // menu page
+	File    string              `json:"file"`    // saved menu page, used instead of URL if set
+	Rules   string              `json:"rules"`   // post-processing rules file, the default rules if empty
+	Times   map[string]schedule `json:"times"`   // times of the meals served, keyed by meal name
+}
+
+// venue is a dining hall with its own menus and meal times
+type venue struct {
+	name    string
+	display string
+	times   map[string]schedule // keyed by menus meal name
+	menus   *menus.Cache        // cached weekly menus
+	archive *menus.Archive      // every menu scraped
+	health  *menus.Monitor      // scraper health monitor
+
+	refreshing sync.Mutex // held while the menus are refreshed, so each change is only announced once
+}
+
+// Reads a JSON list of venues from the file at path
+func loadVenueConfigs(path string) ([]venueConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []venueConfig
+	if err = json.Unmarshal(b, &configs); err != nil {
+		return nil, fmt.Errorf("venues: %v", err)
+	}
+
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("venues: no venues in %v", path)
+	}
+
+	names := make(map[string]bool, len(configs))
+	for i := range configs {
+		configs[i].Name = strings.ToLower(configs[i].Name)
+		if configs[i].Name == "" || names[configs[i].Name] {
+			return nil, fmt.Errorf("venues: venue %v has a missing or duplicate name", i+1)
+		}
+		names[configs[i].Name] = true
+
+		if configs[i].Display == "" {
+			configs[i].Display = configs[i].Name
+		}
+
+		if configs[i].URL == "" && configs[i].File == "" {
+			return nil, fmt.Errorf("venues: venue %v has no menu url or file", configs[i].Name)
+		}
+
+		if len(configs[i].Times) == 0 {
+			return nil, fmt.Errorf("venues: venue %v has no meal times", configs[i].Name)
+		}
+
+		times := make(map[string]schedule, len(configs[i].Times))
+		for name, s := range configs[i].Times {
+			meal, ok := menus.MealName(name)
+			if !ok {
+				return nil, fmt.Errorf("venues: venue %v has times for unknown meal %q", configs[i].Name, name)
+			}
+			if !s.serves() {
+				return nil, fmt.Errorf("venues: venue %v has no times for %v", configs[i].Name, strings.ToLower(meal))
+			}
+			times[meal] = s
+		}
+		configs[i].Times = times
+	}
+
+	return configs, nil
+}
+
+// Creates a venue, storing its menus in the menu and archive buckets with the given suffix
+func newVenue(c venueConfig, db *bolt.DB, bucketSuffix string) (*venue, error) {
+	rules := menus.DefaultRules
+	if c.Rules != "" {
+		var err error
+		if rules, err = menus.LoadRules(c.Rules); err != nil {
+			return nil, err
+		}
+	}
+
+	// A saved copy of the menu page takes precedence over scraping the live site
+	var source menus.Source
+	if c.File != "" {
+		source = menus.File{Path: c.File, Rules: rules, Location: cfg.location, Now: now}
+	} else {
+		source = menus.Scraper{URL: c.URL, Rules: rules, Location: cfg.location, Now: now}
+	}
+
+	v := &venue{
+		name:    c.Name,
+		display: c.Display,
+		times:   c.Times,
+		health:  &menus.Monitor{Threshold: healthThreshold, MaxUnchanged: healthMaxUnchanged, Cooldown: healthCooldown},
+	}
+
+	var err error
+	v.menus, err = menus.NewCache(source, db, getConfigValue("MENU_BUCKET", defaultMenuBucket)+bucketSuffix, maxMenuAge, now)
+	if err != nil {
+		return nil, err
+	}
+
+	v.archive, err = menus.NewArchive(db, getConfigValue("ARCHIVE_BUCKET", archiveBucket)+bucketSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// meals returns the names of the meals served at the venue, in serving order
+func (v *venue) meals() []string {
+	var names []string
+	for _, name := range menus.MealNames {
+		if _, ok := v.times[name]; ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// mealTime returns the time of the named meal at the venue on the date of t, and false if it is not served then
+func (v *venue) mealTime(name string, t time.Time) (mealTime, bool) {
+	s, ok := v.times[name]
+	if !ok {
+		return mealTime{}, false
+	}
+
+	return s.on(t)
+}
+
+// getVenue returns the venue called name, or the default venue if there is no such venue
+func getVenue(name string) *venue {
+	for _, v := range cfg.venues {
+		if v.name == name {
+			return v
+		}
+	}
+
+	return cfg.venues[0]
+}
+
+func venueHandler(sender, text string) {
+	if text == "" {
+		names := make([]string, len(cfg.venues))
+		for i, v := range cfg.venues {
+			names[i] = fmt.Sprintf(" - *%s* (%s)", v.name, v.display)
+		}
+
+		current := getVenue(getPreferences(sender).Venue)
+		responseMessage(sender, fmt.Sprintf(venueList, current.display, strings.Join(names, "\n")), standardQR)
+		return
+	}
+
+	var chosen *venue
+	for _, v := range cfg.venues {
+		if v.name == text || strings.ToLower(v.display) == text {
+			chosen = v
+		}
+	}
+
+	if chosen == nil {
+		responseMessage(sender, venueInvalid, standardQR)
+		return
+	}
+
+	if err := updatePreferences(sender, func(p *preferences) { p.Venue = chosen.name }); err != nil {
+		cfg.debug.Print(err)
+		responseMessage(sender, unexpected, standardQR)
+		return
+	}
+
+	responseMessage(sender, fmt.Sprintf(venueSuccess, chosen.display), standardQR)
+}
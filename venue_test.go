@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ratorx/chumenu-go/menus"
+	"github.com/ratorx/chumenu-go/timeofday"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadVenueConfigs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "venues")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, c := range []struct {
+		name  string
+		json  string
+		valid bool
+	}{
+		{"valid", `[{"name": "Churchill", "url": "http://example.com", "times": {"lunch": {"start": "12:15", "end": "13:45"}}}]`, true},
+		{"file instead of url", `[{"name": "test", "file": "week.html", "times": {"dinner": {"weekdays": {"monday": {"start": "18:00", "end": "19:00"}}}}}]`, true},
+		{"no venues", `[]`, false},
+		{"no name", `[{"url": "http://example.com", "times": {"lunch": {"start": "12:15", "end": "13:45"}}}]`, false},
+		{"duplicate name", `[{"name": "a", "url": "u", "times": {"lunch": {"start": "12:15", "end": "13:45"}}}, {"name": "A", "url": "u", "times": {"lunch": {"start": "12:15", "end": "13:45"}}}]`, false},
+		{"no url or file", `[{"name": "test", "times": {"lunch": {"start": "12:15", "end": "13:45"}}}]`, false},
+		{"no times", `[{"name": "test", "url": "http://example.com"}]`, false},
+		{"empty meal times", `[{"name": "test", "url": "http://example.com", "times": {"lunch": {}}}]`, false},
+		{"missing end", `[{"name": "test", "url": "http://example.com", "times": {"lunch": {"default": {"start": "12:15"}}}}]`, false},
+		{"unknown meal", `[{"name": "test", "url": "http://example.com", "times": {"elevenses": {"start": "11:00", "end": "11:30"}}}]`, false},
+	} {
+		path := filepath.Join(dir, "venues.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(c.json), 0644))
+
+		configs, err := loadVenueConfigs(path)
+		if !c.valid {
+			assert.Error(t, err, c.name)
+			continue
+		}
+		require.NoError(t, err, c.name)
+		require.Len(t, configs, 1, c.name)
+	}
+
+	path := filepath.Join(dir, "venues.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{"name": "Churchill", "url": "http://example.com", "times": {"LUNCH": {"start": "12:15", "end": "13:45"}}}]`), 0644))
+	configs, err := loadVenueConfigs(path)
+	require.NoError(t, err)
+	assert.Equal(t, "churchill", configs[0].Name)
+	assert.Equal(t, "churchill", configs[0].Display)
+	assert.Equal(t, everyDay(mealTime{timeofday.New(12, 15), timeofday.New(13, 45)}), configs[0].Times[menus.Lunch])
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ratorx/chumenu-go/menus"
+)
+
+const (
+	watchBucket = "watches"
+	maxWatches  = 10
+
+	// Watch messages
+	watchSuccess    = "Watching for %q. You will be messaged when it is on the menu."
+	watchFail       = "Already watching for %q."
+	watchLimit      = "Too many watches. Remove one with *unwatch <dish>* first."
+	watchSubscribe  = "Subscribe first to receive dish alerts."
+	unwatchSuccess  = "No longer watching for %q."
+	unwatchFail     = "Not watching for %q."
+	watchesNone     = "Not watching for any dishes. Add one with *watch <dish>*."
+	watchAlertTitle = "Watched dishes on the menu:"
+)
+
+// Returns the terms watched by sender
+func getWatches(b *bolt.Bucket, sender []byte) ([]string, error) {
+	var terms []string
+
+	v := b.Get(sender)
+	if v == nil {
+		return terms, nil
+	}
+
+	err := json.Unmarshal(v, &terms)
+	return terms, err
+}
+
+func putWatches(b *bolt.Bucket, sender []byte, terms []string) error {
+	if len(terms) == 0 {
+		return b.Delete(sender)
+	}
+
+	v, err := json.Marshal(terms)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(sender, v)
+}
+
+func watchHandler(sender, term string) {
+	s := []byte(sender)
+
+	err := cfg.db.Update(func(tx *bolt.Tx) error {
+		users := tx.Bucket([]byte(cfg.userBucket))
+		b := tx.Bucket([]byte(watchBucket))
+		if users == nil || b == nil {
+			return fmt.Errorf("database corrupted: bucket %v or %v not found", cfg.userBucket, watchBucket)
+		}
+
+		if users.Get(s) == nil {
+			go responseMessage(sender, watchSubscribe, unsubscriptionQR)
+			return nil
+		}
+
+		terms, err := getWatches(b, s)
+		if err != nil {
+			go responseMessage(sender, unexpected, standardQR)
+			return err
+		}
+
+		for _, t := range terms {
+			if t == term {
+				go responseMessage(sender, fmt.Sprintf(watchFail, term), standardQR)
+				return nil
+			}
+		}
+
+		if len(terms) >= maxWatches {
+			go responseMessage(sender, watchLimit, standardQR)
+			return nil
+		}
+
+		if err = putWatches(b, s, append(terms, term)); err != nil {
+			go responseMessage(sender, unexpected, standardQR)
+			return err
+		}
+		go responseMessage(sender, fmt.Sprintf(watchSuccess, term), standardQR)
+
+		return nil
+	})
+
+	if err != nil {
+		cfg.debug.Print(err)
+	}
+}
+
+func unwatchHandler(sender, term string) {
+	s := []byte(sender)
+
+	err := cfg.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(watchBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", watchBucket)
+		}
+
+		terms, err := getWatches(b, s)
+		if err != nil {
+			go responseMessage(sender, unexpected, standardQR)
+			return err
+		}
+
+		remaining := make([]string, 0, len(terms))
+		for _, t := range terms {
+			if t != term {
+				remaining = append(remaining, t)
+			}
+		}
+
+		if len(remaining) == len(terms) {
+			go responseMessage(sender, fmt.Sprintf(unwatchFail, term), standardQR)
+			return nil
+		}
+
+		if err = putWatches(b, s, remaining); err != nil {
+			go responseMessage(sender, unexpected, standardQR)
+			return err
+		}
+		go responseMessage(sender, fmt.Sprintf(unwatchSuccess, term), standardQR)
+
+		return nil
+	})
+
+	if err != nil {
+		cfg.debug.Print(err)
+	}
+}
+
+func watchesMessage(sender string) {
+	var terms []string
+
+	err := cfg.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(watchBucket))
+		if b == nil {
+			return fmt.Errorf("database corrupted: bucket %v not found", watchBucket)
+		}
+
+		var err error
+		terms, err = getWatches(b, []byte(sender))
+		return err
+	})
+
+	if err != nil {
+		cfg.debug.Print(err)
+		responseMessage(sender, unexpected, standardQR)
+		return
+	}
+
+	if len(terms) == 0 {
+		responseMessage(sender, watchesNone, standardQR)
+		return
+	}
+
+	responseMessage(sender, "Watching for:\n - "+strings.Join(terms, "\n - "), standardQR)
+}
+
+func matchKey(m menus.Match) string {
+	return menus.DateKey(m.Date) + "/" + m.Meal + "/" + m.Item.Name
+}
+
+// watchAlerts messages subscribers at v about upcoming items in new matching their watches.
+// Items which were already in old have been alerted before, so are skipped.
+func watchAlerts(v *venue, old, new menus.Week) {
+	today := now()
+	y, m, d := today.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, today.Location())
+
+	seen := make(map[string]bool)
+	for _, day := range old {
+		for _, meal := range day.Meals {
+			for _, item := range meal.Items {
+				seen[matchKey(menus.Match{Date: day.Date, Meal: meal.Name, Item: item})] = true
+			}
+		}
+	}
+
+	var num uint
+	err := cfg.db.View(func(tx *bolt.Tx) error {
+		users := tx.Bucket([]byte(cfg.userBucket))
+		prefs := tx.Bucket([]byte(preferenceBucket))
+		b := tx.Bucket([]byte(watchBucket))
+		if users == nil || prefs == nil || b == nil {
+			return fmt.Errorf("database corrupted: bucket %v, %v or %v not found", cfg.userBucket, preferenceBucket, watchBucket)
+		}
+
+		return b.ForEach(func(k, value []byte) error {
+			if users.Get(k) == nil {
+				return nil
+			}
+
+			// A corrupt record only affects its own user
+			p, err := loadPreferences(prefs, k)
+			if err != nil {
+				cfg.debug.Print(err)
+				return nil
+			}
+
+			if getVenue(p.Venue) != v {
+				return nil
+			}
+
+			var terms []string
+			if err = json.Unmarshal(value, &terms); err != nil {
+				cfg.debug.Print(err)
+				return nil
+			}
+
+			var lines []string
+			alerted := make(map[string]bool)
+			for _, term := range terms {
+				for _, match := range menus.Search(new, term) {
+					key := matchKey(match)
+					if !match.Date.Before(start) && !seen[key] && !alerted[key] {
+						lines = append(lines, formatMatch(match))
+						alerted[key] = true
+					}
+				}
+			}
+
+			if len(lines) != 0 {
+				go subscriptionMessage(string(k), watchAlertTitle+"\n"+strings.Join(lines, "\n"), subscriptionQR)
+				num++
+			}
+			return nil
+		})
+	})
+
+	if err != nil {
+		cfg.debug.Println(err)
+	} else if num != 0 {
+		cfg.debug.Printf("timed message send attempt for %v watch alerts to %v users", v.name, num)
+	}
+}